@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+var checkCollectors []string
+
+// checkCmd validates the credentials against the APIs used by the collectors.
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the credentials can access the APIs used by the collectors",
+	Long: `Obtain an OAuth token, call the APIs used by all or the selected collectors
+and print whether each collector works, lacks a scope or fails otherwise.
+
+Exits with a non-zero status if any collector fails, so new credentials can be
+validated before rollout.`,
+	SilenceUsage: true,
+	RunE:         runCheck,
+}
+
+func init() {
+	checkCmd.Flags().
+		StringSliceVar(&checkCollectors, "collectors", nil, "Collectors to check (default all), one of: "+strings.Join(collector.CollectorNames(), ", "))
+
+	rootCmd.AddCommand(checkCmd)
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+
+	httpClient, _, err := newOAuthHTTPClient(logger)
+	if err != nil {
+		return err
+	}
+
+	// Collector errors are reported in the table instead of logged
+	tsCollector, err := newTailscaleCollector(
+		slog.New(slog.DiscardHandler),
+		httpClient,
+		collector.WithCollectors(checkCollectors...),
+	)
+	if err != nil {
+		return err
+	}
+
+	results := tsCollector.Check(context.Background())
+	if err := printCheckResults(cmd.OutOrStdout(), results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != collector.CheckOK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d collectors failed the check", failed, len(results))
+	}
+	return nil
+}
+
+func printCheckResults(w io.Writer, results []collector.CheckResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTOR\tSCOPES\tSTATUS\tERROR")
+	for _, result := range results {
+		errMsg := ""
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			result.Collector,
+			strings.Join(result.Scopes, ","),
+			result.Status,
+			errMsg,
+		)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+// fileConfig is the YAML configuration file, which replaces the tailnet,
+// OAuth and collector flags.
+type fileConfig struct {
+	Tailnets   []tailnetConfig  `yaml:"tailnets"`
+	Collectors collectorsConfig `yaml:"collectors"`
+}
+
+// tailnetConfig configures a tailnet to collect metrics from.
+type tailnetConfig struct {
+	Name  string      `yaml:"name"`
+	OAuth oauthConfig `yaml:"oauth"`
+	// Collectors overrides the enabled collectors for this tailnet.
+	Collectors []string `yaml:"collectors"`
+	StatePath  string   `yaml:"state_path"`
+}
+
+// oauthConfig holds the OAuth client credentials of a tailnet.
+type oauthConfig struct {
+	ClientID         string `yaml:"client_id"`
+	ClientSecret     string `yaml:"client_secret"`
+	ClientSecretFile string `yaml:"client_secret_file"`
+}
+
+// collectorsConfig configures the collectors of all tailnets.
+type collectorsConfig struct {
+	Enabled          []string          `yaml:"enabled"`
+	AggregateOnly    bool              `yaml:"aggregate_only"`
+	ForbiddenBackoff time.Duration     `yaml:"forbidden_backoff"`
+	LogLevels        map[string]string `yaml:"log_levels"`
+	ExpiryWindows    []time.Duration   `yaml:"expiry_windows"`
+	Policy           policyConfig      `yaml:"policy"`
+	Invites          invitesConfig     `yaml:"invites"`
+}
+
+// policyConfig configures the policy collector.
+type policyConfig struct {
+	TestsFile     string        `yaml:"tests_file"`
+	TestsInterval time.Duration `yaml:"tests_interval"`
+}
+
+// invitesConfig configures the invites collector.
+type invitesConfig struct {
+	DeviceInterval time.Duration `yaml:"device_interval"`
+}
+
+// loadConfigFile reads and validates the configuration file at path.
+// Unknown fields are rejected, so typos do not go unnoticed.
+func loadConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &fileConfig{
+		Collectors: collectorsConfig{
+			ForbiddenBackoff: collector.DefaultForbiddenBackoff,
+			ExpiryWindows:    collector.DefaultExpiryWindows(),
+			Policy:           policyConfig{TestsInterval: collector.DefaultPolicyTestsInterval},
+			Invites:          invitesConfig{DeviceInterval: collector.DefaultDeviceInvitesInterval},
+		},
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// validate checks the configuration and reads the OAuth client secret files.
+func (c *fileConfig) validate() error {
+	if len(c.Tailnets) == 0 {
+		return errors.New("at least one tailnet is required")
+	}
+	if err := validateCollectorNames(c.Collectors.Enabled); err != nil {
+		return fmt.Errorf("collectors.enabled: %w", err)
+	}
+	if c.Collectors.ForbiddenBackoff <= 0 {
+		return errors.New("collectors.forbidden_backoff must be positive")
+	}
+	if c.Collectors.Policy.TestsInterval <= 0 {
+		return errors.New("collectors.policy.tests_interval must be positive")
+	}
+	if c.Collectors.Invites.DeviceInterval <= 0 {
+		return errors.New("collectors.invites.device_interval must be positive")
+	}
+	windows := make(map[time.Duration]bool)
+	for _, window := range c.Collectors.ExpiryWindows {
+		if window <= 0 || windows[window] {
+			return errors.New("collectors.expiry_windows must be positive and unique")
+		}
+		windows[window] = true
+	}
+	if _, err := parseCollectorLogLevels(c.Collectors.LogLevels); err != nil {
+		return fmt.Errorf("collectors.log_levels: %w", err)
+	}
+
+	names := make(map[string]bool)
+	statePaths := make(map[string]bool)
+	for i := range c.Tailnets {
+		tc := &c.Tailnets[i]
+		if tc.Name == "" {
+			return fmt.Errorf("tailnets[%d]: name is required", i)
+		}
+		if names[tc.Name] {
+			return fmt.Errorf("tailnets[%d]: duplicate tailnet %q", i, tc.Name)
+		}
+		names[tc.Name] = true
+		if tc.StatePath != "" {
+			if statePaths[tc.StatePath] {
+				return fmt.Errorf("tailnet %s: state_path %s is used by another tailnet",
+					tc.Name, tc.StatePath)
+			}
+			statePaths[tc.StatePath] = true
+		}
+		if err := validateCollectorNames(tc.Collectors); err != nil {
+			return fmt.Errorf("tailnet %s: collectors: %w", tc.Name, err)
+		}
+		if err := tc.OAuth.resolve(); err != nil {
+			return fmt.Errorf("tailnet %s: oauth: %w", tc.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolve checks the OAuth client credentials, reading the client secret from
+// its file if set.
+func (o *oauthConfig) resolve() error {
+	if o.ClientID == "" {
+		return errors.New("client_id is required")
+	}
+	switch {
+	case o.ClientSecret != "" && o.ClientSecretFile != "":
+		return errors.New("only one of client_secret and client_secret_file can be set")
+	case o.ClientSecretFile != "":
+		secret, err := os.ReadFile(o.ClientSecretFile)
+		if err != nil {
+			return fmt.Errorf("reading client_secret_file: %w", err)
+		}
+		o.ClientSecret = strings.TrimSpace(string(secret))
+	}
+	if o.ClientSecret == "" {
+		return errors.New("client_secret or client_secret_file is required")
+	}
+	return nil
+}
+
+func validateCollectorNames(names []string) error {
+	known := collector.CollectorNames()
+	for _, name := range names {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("unknown collector %q, must be one of: %s",
+				name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// targets creates a collector for every tailnet, carrying the state of the
+// collectors in prev over.
+func (c *fileConfig) targets(logger *slog.Logger, prev map[string]*target) ([]*target, error) {
+	logLevels, err := parseCollectorLogLevels(c.Collectors.LogLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*target, 0, len(c.Tailnets))
+	for _, tc := range c.Tailnets {
+		enabled := c.Collectors.Enabled
+		if len(tc.Collectors) > 0 {
+			enabled = tc.Collectors
+		}
+
+		tailnetLogger := logger.With("tailnet", tc.Name)
+		httpClient, tokenSource, err := oauthHTTPClient(
+			tailnetLogger,
+			tc.OAuth.ClientID,
+			tc.OAuth.ClientSecret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("tailnet %s: %w", tc.Name, err)
+		}
+
+		opts := []collector.Option{
+			collector.WithCollectors(enabled...),
+			collector.WithPolicyTests(
+				c.Collectors.Policy.TestsFile,
+				c.Collectors.Policy.TestsInterval,
+			),
+			collector.WithDeviceInvitesInterval(c.Collectors.Invites.DeviceInterval),
+			collector.WithAggregateOnly(c.Collectors.AggregateOnly),
+			collector.WithStatePath(tc.StatePath),
+			collector.WithForbiddenBackoff(c.Collectors.ForbiddenBackoff),
+			collector.WithLogLevels(logLevels),
+			collector.WithExpiryWindows(c.Collectors.ExpiryWindows...),
+		}
+		if p, ok := prev[tc.Name]; ok {
+			opts = append(opts, collector.WithPrevious(p.collector))
+		}
+		tsCollector, err := collector.NewTailscaleCollector(
+			tailnetLogger,
+			httpClient,
+			tc.Name,
+			opts...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("tailnet %s: %w", tc.Name, err)
+		}
+
+		targets = append(targets, &target{
+			tailnet:     tc.Name,
+			collector:   tsCollector,
+			tokenSource: tokenSource,
+		})
+	}
+	return targets, nil
+}
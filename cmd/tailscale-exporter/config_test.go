@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretFile, []byte("tskey-client-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		config      string
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			config: `
+tailnets:
+  - name: example.com
+    oauth:
+      client_id: id
+      client_secret_file: ` + secretFile + `
+  - name: example.org
+    oauth:
+      client_id: id
+      client_secret: secret
+    collectors: [devices]
+collectors:
+  enabled: [devices, users]
+  forbidden_backoff: 30m
+  expiry_windows: [24h, 168h]
+  log_levels:
+    devices: debug
+`,
+		},
+		{
+			name:        "no tailnets",
+			config:      "collectors: {}\n",
+			expectedErr: "at least one tailnet is required",
+		},
+		{
+			name: "unknown field",
+			config: `
+tailnets:
+  - name: example.com
+    oauth: {client_id: id, client_secret: secret}
+    tailnet: example.com
+`,
+			expectedErr: "field tailnet not found",
+		},
+		{
+			name: "unknown collector",
+			config: `
+tailnets:
+  - name: example.com
+    oauth: {client_id: id, client_secret: secret}
+    collectors: [devicez]
+`,
+			expectedErr: `unknown collector "devicez"`,
+		},
+		{
+			name: "duplicate tailnet",
+			config: `
+tailnets:
+  - name: example.com
+    oauth: {client_id: id, client_secret: secret}
+  - name: example.com
+    oauth: {client_id: id, client_secret: secret}
+`,
+			expectedErr: `duplicate tailnet "example.com"`,
+		},
+		{
+			name: "missing secret",
+			config: `
+tailnets:
+  - name: example.com
+    oauth: {client_id: id}
+`,
+			expectedErr: "client_secret or client_secret_file is required",
+		},
+		{
+			name: "invalid log level",
+			config: `
+tailnets:
+  - name: example.com
+    oauth: {client_id: id, client_secret: secret}
+collectors:
+  log_levels: {devices: loud}
+`,
+			expectedErr: "collectors.log_levels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := loadConfigFile(path)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cfg.Tailnets[0].OAuth.ClientSecret; got != "tskey-client-secret" {
+				t.Errorf("expected the secret to be read from its file, got %q", got)
+			}
+			if cfg.Collectors.ForbiddenBackoff != 30*time.Minute {
+				t.Errorf(
+					"expected forbidden backoff of 30m, got %v",
+					cfg.Collectors.ForbiddenBackoff,
+				)
+			}
+			if len(cfg.Collectors.ExpiryWindows) != 2 ||
+				cfg.Collectors.ExpiryWindows[0] != 24*time.Hour {
+				t.Errorf(
+					"expected expiry windows of 24h and 168h, got %v",
+					cfg.Collectors.ExpiryWindows,
+				)
+			}
+			if cfg.Collectors.Policy.TestsInterval != 5*time.Minute {
+				t.Errorf("expected default policy tests interval, got %v",
+					cfg.Collectors.Policy.TestsInterval)
+			}
+			if cfg.Collectors.Invites.DeviceInterval != collector.DefaultDeviceInvitesInterval {
+				t.Errorf("expected default device invites interval, got %v",
+					cfg.Collectors.Invites.DeviceInterval)
+			}
+		})
+	}
+}
+
+func TestExporterReload(t *testing.T) {
+	newTarget := func(tailnet string) *target {
+		tsCollector, err := collector.NewTailscaleCollector(
+			slog.Default(),
+			nil,
+			tailnet,
+			collector.WithCollectors("webhooks"),
+		)
+		if err != nil {
+			t.Fatalf("creating collector: %v", err)
+		}
+		return &target{tailnet: tailnet, collector: tsCollector}
+	}
+
+	var buildErr error
+	tailnets := []string{"example.com"}
+	exp, err := newExporter(slog.Default(), func(map[string]*target) ([]*target, error) {
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		var targets []*target
+		for _, tailnet := range tailnets {
+			targets = append(targets, newTarget(tailnet))
+		}
+		return targets, nil
+	})
+	if err != nil {
+		t.Fatalf("creating exporter: %v", err)
+	}
+
+	reload := func() int {
+		rec := httptest.NewRecorder()
+		exp.ReloadHandler(true).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+		return rec.Code
+	}
+
+	tailnets = []string{"example.com", "example.org"}
+	if code := reload(); code != http.StatusOK {
+		t.Fatalf("expected successful reload, got %d", code)
+	}
+	if got := len(exp.currentTargets()); got != 2 {
+		t.Errorf("expected 2 tailnets after reload, got %d", got)
+	}
+	if got := testutil.ToFloat64(configReloadSuccess); got != 1 {
+		t.Errorf("expected last reload to be successful, got %v", got)
+	}
+	if got, err := testutil.GatherAndCount(exp, "tailscale_up"); err != nil || got != 2 {
+		t.Errorf("expected tailscale_up for both tailnets, got %d, %v", got, err)
+	}
+
+	buildErr = errors.New("invalid configuration")
+	if code := reload(); code != http.StatusInternalServerError {
+		t.Fatalf("expected failed reload, got %d", code)
+	}
+	if got := len(exp.currentTargets()); got != 2 {
+		t.Errorf("expected the previous tailnets to keep serving, got %d", got)
+	}
+	if got := testutil.ToFloat64(configReloadSuccess); got != 0 {
+		t.Errorf("expected last reload to be unsuccessful, got %v", got)
+	}
+
+	if _, err := exp.target(""); err == nil {
+		t.Error("expected the tailnet to be required with multiple tailnets")
+	}
+	if tgt, err := exp.target("example.org"); err != nil || tgt.tailnet != "example.org" {
+		t.Errorf("expected example.org target, got %v, %v", tgt, err)
+	}
+
+	rec := httptest.NewRecorder()
+	exp.ReloadHandler(true).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/reload", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET to be rejected, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	exp.ReloadHandler(false).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected reload to be forbidden without the lifecycle API, got %d", rec.Code)
+	}
+}
+
+func TestExporterPolicyGroups(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/tailnet/example.com/acl":
+			_, _ = w.Write([]byte(
+				`{"groups":{"group:sre":["alice@example.com","bob@example.org"]}}`,
+			))
+		case "/api/v2/tailnet/example.org/acl":
+			_, _ = w.Write([]byte(
+				`{"groups":{"group:sre":["bob@example.org"],"group:dev":["carol@example.org"]}}`,
+			))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"forbidden"}`))
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := &http.Client{
+		Transport: rewriteTransport{target: serverURL, base: http.DefaultTransport},
+	}
+
+	newExporterOf := func(tailnets ...string) *exporter {
+		exp, err := newExporter(slog.Default(), func(map[string]*target) ([]*target, error) {
+			var targets []*target
+			for _, tailnet := range tailnets {
+				tsCollector, err := collector.NewTailscaleCollector(
+					slog.Default(),
+					httpClient,
+					tailnet,
+					collector.WithCollectors("policy"),
+				)
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, &target{tailnet: tailnet, collector: tsCollector})
+			}
+			return targets, nil
+		})
+		if err != nil {
+			t.Fatalf("creating exporter: %v", err)
+		}
+		return exp
+	}
+
+	multi := newExporterOf("example.com", "example.org")
+	tests := []struct {
+		name        string
+		exp         *exporter
+		tailnet     string
+		expected    map[string][]string
+		expectError bool
+	}{
+		{
+			name:    "local tailnet only",
+			exp:     multi,
+			tailnet: "example.org",
+			expected: map[string][]string{
+				"group:sre": {"bob@example.org"},
+				"group:dev": {"carol@example.org"},
+			},
+		},
+		{
+			name:        "local tailnet not configured",
+			exp:         multi,
+			tailnet:     "example.net",
+			expectError: true,
+		},
+		{
+			name:     "single tailnet whatever its name",
+			exp:      newExporterOf("example.com"),
+			tailnet:  "example-tailnet.ts.net",
+			expected: map[string][]string{"group:sre": {"alice@example.com", "bob@example.org"}},
+		},
+		{
+			name:        "policy file not readable",
+			exp:         newExporterOf("example.net"),
+			tailnet:     "example.net",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := tt.exp.policyGroups(context.Background(), tt.tailnet)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(groups, tt.expected) {
+				t.Errorf("expected groups %v, got %v", tt.expected, groups)
+			}
+		})
+	}
+}
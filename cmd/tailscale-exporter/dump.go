@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+const (
+	dumpFormatText        = "text"
+	dumpFormatOpenMetrics = "openmetrics"
+)
+
+var (
+	dumpCollectors       []string
+	dumpFormat           string
+	dumpOutput           string
+	dumpTextfileInterval time.Duration
+)
+
+// dumpCmd runs the collectors once and writes the metrics out.
+var dumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Collect metrics once and write them to stdout or a file",
+	Long: `Run all or the selected collectors once and write the metrics in the
+Prometheus text format or OpenMetrics to stdout or a file.
+
+Files are replaced atomically, so they can be read by the node_exporter
+textfile collector. With --textfile.interval, the file is rewritten on that
+interval until the process is stopped.`,
+	RunE: runDump,
+}
+
+func init() {
+	dumpCmd.Flags().
+		StringSliceVar(&dumpCollectors, "collectors", nil, "Collectors to run (default all), one of: "+strings.Join(collector.CollectorNames(), ", "))
+	dumpCmd.Flags().
+		StringVar(&dumpFormat, "format", dumpFormatText, "Output format, text or openmetrics")
+	dumpCmd.Flags().
+		StringVarP(&dumpOutput, "output", "o", "", "File to write the metrics to (default stdout)")
+	dumpCmd.Flags().
+		DurationVar(&dumpTextfileInterval, "textfile.interval", 0, "Rewrite the output file on this interval instead of exiting after the first dump")
+
+	rootCmd.AddCommand(dumpCmd)
+}
+
+func runDump(cmd *cobra.Command, args []string) error {
+	// Log to stderr so logs never end up in the dumped metrics
+	logger, err := newLogger(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	var format expfmt.Format
+	switch dumpFormat {
+	case dumpFormatText:
+		format = expfmt.NewFormat(expfmt.TypeTextPlain)
+	case dumpFormatOpenMetrics:
+		format = expfmt.NewFormat(expfmt.TypeOpenMetrics)
+	default:
+		return fmt.Errorf("unsupported format %q, must be %q or %q",
+			dumpFormat, dumpFormatText, dumpFormatOpenMetrics)
+	}
+	if dumpTextfileInterval > 0 && dumpOutput == "" {
+		return errors.New("--textfile.interval requires --output")
+	}
+
+	httpClient, _, err := newOAuthHTTPClient(logger)
+	if err != nil {
+		return err
+	}
+
+	tsCollector, err := newTailscaleCollector(
+		logger,
+		httpClient,
+		collector.WithCollectors(dumpCollectors...),
+	)
+	if err != nil {
+		return err
+	}
+
+	reg := prometheus.NewRegistry()
+	prometheus.WrapRegistererWith(prometheus.Labels{"tailnet": tailnet}, reg).
+		MustRegister(tsCollector)
+
+	if dumpTextfileInterval == 0 {
+		return dump(reg, format, dumpOutput)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(dumpTextfileInterval)
+	defer ticker.Stop()
+	for {
+		if err := dump(reg, format, dumpOutput); err != nil {
+			logger.Error("Error writing metrics", "path", dumpOutput, "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dump gathers the metrics of gatherer and writes them to path, or to stdout
+// if path is empty. It returns an error naming the collectors that failed.
+func dump(gatherer prometheus.Gatherer, format expfmt.Format, path string) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	if path == "" {
+		err = encodeMetrics(os.Stdout, format, families)
+	} else {
+		err = collector.WriteFileAtomic(path, 0o644, func(w io.Writer) error {
+			return encodeMetrics(w, format, families)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed := failedCollectors(families); len(failed) > 0 {
+		return fmt.Errorf("collectors failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func encodeMetrics(w io.Writer, format expfmt.Format, families []*dto.MetricFamily) error {
+	enc := expfmt.NewEncoder(w, format)
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return err
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// failedCollectors returns the collectors whose scrape_collector_success
+// metric is 0.
+func failedCollectors(families []*dto.MetricFamily) []string {
+	var failed []string
+	for _, family := range families {
+		if family.GetName() != "tailscale_scrape_collector_success" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if m.GetGauge().GetValue() != 0 {
+				continue
+			}
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "collector" {
+					failed = append(failed, l.GetValue())
+				}
+			}
+		}
+	}
+	return failed
+}
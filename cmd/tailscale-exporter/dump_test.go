@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestDump_File(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	success := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "tailscale_scrape_collector_success"},
+		[]string{"collector"},
+	)
+	success.WithLabelValues("devices").Set(1)
+	success.WithLabelValues("users").Set(0)
+	reg.MustRegister(success)
+
+	path := filepath.Join(t.TempDir(), "tailscale.prom")
+	err := dump(reg, expfmt.NewFormat(expfmt.TypeOpenMetrics), path)
+	if err == nil || !strings.Contains(err.Error(), "users") {
+		t.Errorf("expected an error naming the failed collector, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading dump: %v", err)
+	}
+	if !strings.Contains(
+		string(data),
+		`tailscale_scrape_collector_success{collector="devices"} 1`,
+	) {
+		t.Errorf("expected metrics in dump, got:\n%s", data)
+	}
+	if !strings.HasSuffix(string(data), "# EOF\n") {
+		t.Errorf("expected OpenMetrics EOF marker, got:\n%s", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("expected mode 0644, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected temporary files to be cleaned up, got %v", entries)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+// readyRefreshInterval limits how often the readiness endpoint runs the
+// collectors itself when no scrape has succeeded recently.
+const readyRefreshInterval = time.Minute
+
+// readiness is the JSON body of the readiness endpoint.
+type readiness struct {
+	Ready    bool                        `json:"ready"`
+	Tailnets map[string]tailnetReadiness `json:"tailnets"`
+}
+
+// tailnetReadiness is the readiness of a single tailnet.
+type tailnetReadiness struct {
+	Ready       bool                                 `json:"ready"`
+	Token       string                               `json:"token"`
+	LastSuccess *time.Time                           `json:"last_success,omitempty"`
+	Collectors  map[string]collector.CollectorStatus `json:"collectors"`
+}
+
+// healthyHandler reports that the process is alive.
+func healthyHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyHandler reports ready once, for every tailnet, an OAuth token can be
+// obtained and a collector succeeded within window. If none did, it runs the
+// collectors in the background, at most every readyRefreshInterval, so
+// readiness does not depend on scrapes, which may only target ready pods.
+type readyHandler struct {
+	logger  *slog.Logger
+	targets func() []*target
+	window  time.Duration
+
+	mtx           sync.Mutex
+	refreshing    bool
+	lastRefreshed time.Time
+}
+
+func (h *readyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res := readiness{
+		Ready:    true,
+		Tailnets: make(map[string]tailnetReadiness),
+	}
+
+	var stale []*collector.TailscaleCollector
+	for _, t := range h.targets() {
+		tr := tailnetReadiness{
+			Ready:      true,
+			Token:      "ok",
+			Collectors: t.collector.Status(),
+		}
+
+		if _, err := t.tokenSource.Token(); err != nil {
+			tr.Ready = false
+			tr.Token = err.Error()
+		}
+
+		lastSuccess := t.collector.LastSuccess()
+		if !lastSuccess.IsZero() {
+			tr.LastSuccess = &lastSuccess
+		}
+		if time.Since(lastSuccess) > h.window {
+			tr.Ready = false
+			stale = append(stale, t.collector)
+		}
+
+		res.Ready = res.Ready && tr.Ready
+		res.Tailnets[t.tailnet] = tr
+	}
+	if len(stale) > 0 {
+		h.refresh(stale)
+	}
+
+	status := http.StatusOK
+	if !res.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealthJSON(w, status, res)
+}
+
+// refresh runs the collectors in the background unless they are already
+// running or ran recently.
+func (h *readyHandler) refresh(collectors []*collector.TailscaleCollector) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if h.refreshing || time.Since(h.lastRefreshed) < readyRefreshInterval {
+		return
+	}
+	h.refreshing = true
+	h.lastRefreshed = time.Now()
+
+	go func() {
+		h.logger.Debug("No recent successful collection, refreshing")
+		for _, c := range collectors {
+			c.Refresh(context.Background())
+		}
+
+		h.mtx.Lock()
+		h.refreshing = false
+		h.mtx.Unlock()
+	}()
+}
+
+func writeHealthJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+func TestReadyHandler(t *testing.T) {
+	// Every API call succeeds with no webhooks
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"webhooks":[]}`)),
+			}, nil
+		}),
+	}
+	tsCollector, err := collector.NewTailscaleCollector(
+		slog.Default(),
+		httpClient,
+		"example.com",
+		collector.WithCollectors("webhooks"),
+	)
+	if err != nil {
+		t.Fatalf("creating collector: %v", err)
+	}
+
+	var tokenErr error
+	targets := []*target{{
+		tailnet:   "example.com",
+		collector: tsCollector,
+		tokenSource: tokenSourceFunc(func() (*oauth2.Token, error) {
+			return &oauth2.Token{AccessToken: "token"}, tokenErr
+		}),
+	}}
+	handler := &readyHandler{
+		logger:  slog.Default(),
+		targets: func() []*target { return targets },
+		window:  time.Minute,
+	}
+
+	ready := func() (int, readiness) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/-/ready", nil))
+
+		var res readiness
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		return rec.Code, res
+	}
+
+	// Not ready before any collection, which triggers a refresh
+	if code, res := ready(); code != http.StatusServiceUnavailable || res.Ready {
+		t.Errorf("expected not ready before the first collection, got %d %+v", code, res)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tsCollector.LastSuccess().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	code, res := ready()
+	if code != http.StatusOK || !res.Ready {
+		t.Errorf("expected ready after a successful collection, got %d %+v", code, res)
+	}
+	collectors := res.Tailnets["example.com"].Collectors
+	if status, ok := collectors["webhooks"]; !ok || !status.Enabled || status.LastSuccess == nil {
+		t.Errorf("expected webhooks collector status, got %+v", collectors)
+	}
+
+	tokenErr = errors.New("invalid_client")
+	if code, res := ready(); code != http.StatusServiceUnavailable ||
+		res.Tailnets["example.com"].Token != "invalid_client" {
+		t.Errorf("expected not ready without a token, got %d %+v", code, res)
+	}
+}
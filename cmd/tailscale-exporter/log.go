@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+const (
+	logFormatLogfmt = "logfmt"
+	logFormatJSON   = "json"
+)
+
+// newLogger creates a logger writing to w, configured by the log flags.
+func newLogger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid --log.level: %w", err)
+	}
+
+	// The base handler accepts all levels, so collectors can log below
+	// --log.level; the level is enforced by the collector handler.
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch logFormat {
+	case logFormatLogfmt:
+		handler = slog.NewTextHandler(w, opts)
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format %q, must be %q or %q",
+			logFormat, logFormatLogfmt, logFormatJSON)
+	}
+
+	return slog.New(collector.NewLogHandler(handler, level)), nil
+}
+
+// collectorLogLevels parses the --log.collector-level flag.
+func collectorLogLevels() (map[string]slog.Level, error) {
+	levels, err := parseCollectorLogLevels(logCollectorLevels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --log.collector-level: %w", err)
+	}
+	return levels, nil
+}
+
+// parseCollectorLogLevels parses log levels by collector name.
+func parseCollectorLogLevels(values map[string]string) (map[string]slog.Level, error) {
+	names := collector.CollectorNames()
+	levels := make(map[string]slog.Level, len(values))
+	for name, value := range values {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("unknown collector %q, must be one of: %s",
+				name, strings.Join(names, ", "))
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return nil, fmt.Errorf("collector %s: %w", name, err)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
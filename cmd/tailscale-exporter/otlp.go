@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prometheusbridge "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	otlpProtocolGRPC = "grpc"
+	otlpProtocolHTTP = "http"
+)
+
+// newOTLPExporter creates an OTLP metric exporter for protocol, sending to
+// endpoint, a URL such as http://otel-collector:4317.
+func newOTLPExporter(
+	ctx context.Context,
+	protocol, endpoint string,
+	headers map[string]string,
+) (sdkmetric.Exporter, error) {
+	switch protocol {
+	case otlpProtocolGRPC:
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(endpoint),
+			otlpmetricgrpc.WithHeaders(headers),
+		)
+	case otlpProtocolHTTP:
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpointURL(endpoint),
+			otlpmetrichttp.WithHeaders(headers),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, must be %q or %q",
+			protocol, otlpProtocolGRPC, otlpProtocolHTTP)
+	}
+}
+
+// startOTLPPush periodically gathers the metrics from gatherer, which runs
+// the registered collectors, and pushes them to an OTLP endpoint. Every push
+// is a full API sweep, independent of Prometheus scrapes. The returned
+// function flushes pending metrics and stops pushing.
+func startOTLPPush(
+	ctx context.Context,
+	gatherer prometheus.Gatherer,
+	protocol, endpoint string,
+	headers map[string]string,
+	interval time.Duration,
+) (func(context.Context) error, error) {
+	exporter, err := newOTLPExporter(ctx, protocol, endpoint, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", "tailscale-exporter"),
+		attribute.String("service.version", version),
+	}
+	// A configuration file can define several tailnets, told apart by the
+	// tailnet label of the metrics
+	if configFile == "" {
+		attrs = append(attrs, attribute.String("tailscale.tailnet", tailnet))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP resource: %w", err)
+	}
+
+	reader := sdkmetric.NewPeriodicReader(
+		exporter,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(
+			prometheusbridge.NewMetricProducer(prometheusbridge.WithGatherer(gatherer)),
+		),
+	)
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(res),
+	)
+
+	return provider.Shutdown, nil
+}
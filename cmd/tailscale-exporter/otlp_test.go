@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPPush(t *testing.T) {
+	requests := make(chan *collectormetrics.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("expected the configured headers, got authorization %q", got)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+		}
+		req := &collectormetrics.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		requests <- req
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		resp, _ := proto.Marshal(&collectormetrics.ExportMetricsServiceResponse{})
+		_, _ = w.Write(resp)
+	}))
+	defer server.Close()
+
+	prevConfigFile, prevTailnet := configFile, tailnet
+	configFile, tailnet = "", "example.com"
+	t.Cleanup(func() { configFile, tailnet = prevConfigFile, prevTailnet })
+
+	registry := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "tailscale_up", Help: "Up."})
+	up.Set(1)
+	registry.MustRegister(up)
+
+	shutdown, err := startOTLPPush(
+		context.Background(),
+		registry,
+		otlpProtocolHTTP,
+		server.URL+"/v1/metrics",
+		map[string]string{"Authorization": "Bearer token"},
+		time.Hour,
+	)
+	if err != nil {
+		t.Fatalf("starting OTLP push: %v", err)
+	}
+	// Shutting down flushes the metrics, well before the interval
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutting down OTLP push: %v", err)
+	}
+
+	var req *collectormetrics.ExportMetricsServiceRequest
+	select {
+	case req = <-requests:
+	default:
+		t.Fatal("expected metrics to be pushed")
+	}
+	if len(req.ResourceMetrics) != 1 {
+		t.Fatalf("expected 1 resource, got %d", len(req.ResourceMetrics))
+	}
+
+	attrs := make(map[string]string)
+	for _, attr := range req.ResourceMetrics[0].Resource.Attributes {
+		attrs[attr.Key] = attr.Value.GetStringValue()
+	}
+	for key, expected := range map[string]string{
+		"service.name":      "tailscale-exporter",
+		"service.version":   version,
+		"tailscale.tailnet": "example.com",
+	} {
+		if attrs[key] != expected {
+			t.Errorf("expected resource attribute %s=%q, got %q", key, expected, attrs[key])
+		}
+	}
+
+	found := false
+	for _, scope := range req.ResourceMetrics[0].ScopeMetrics {
+		for _, metric := range scope.Metrics {
+			if metric.Name != "tailscale_up" {
+				continue
+			}
+			found = true
+			points := metric.GetGauge().GetDataPoints()
+			if len(points) != 1 || points[0].GetAsDouble() != 1 {
+				t.Errorf("expected tailscale_up to be 1, got %v", points)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected tailscale_up to be pushed")
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	pushTargetRemoteWrite = "remote_write"
+	pushTargetPushgateway = "pushgateway"
+)
+
+// pushRetryBackoff is the wait before the first retry of a failed push,
+// doubled for every further retry. Tests shorten it.
+var pushRetryBackoff = time.Second
+
+var pushFailures = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "tailscale_exporter_push_failures_total",
+		Help: "Number of failed attempts to push metrics.",
+	},
+	[]string{"target"},
+)
+
+// pushFunc pushes metric families to a single target.
+type pushFunc func(ctx context.Context, families []*dto.MetricFamily) error
+
+// permanentPushError is a push error that retrying cannot fix, e.g. because
+// the target rejected the metrics.
+type permanentPushError struct {
+	err error
+}
+
+func (e permanentPushError) Error() string { return e.err.Error() }
+
+func (e permanentPushError) Unwrap() error { return e.err }
+
+// pushAuth holds the credentials sent with push requests. Secrets may be
+// read from files instead, so they don't show up in the process arguments.
+type pushAuth struct {
+	username        string
+	password        string
+	passwordFile    string
+	bearerToken     string
+	bearerTokenFile string
+}
+
+// validate checks that every secret is set at most once.
+func (a pushAuth) validate() error {
+	if a.password != "" && a.passwordFile != "" {
+		return errors.New(
+			"at most one of --push.basic-auth-password and --push.basic-auth-password-file can be set",
+		)
+	}
+	if a.bearerToken != "" && a.bearerTokenFile != "" {
+		return errors.New(
+			"at most one of --push.bearer-token and --push.bearer-token-file can be set",
+		)
+	}
+	return nil
+}
+
+// readSecret returns value, or the contents of file if set, without
+// surrounding whitespace. Files are read on every request, so rotated secrets
+// are picked up.
+func readSecret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// authRoundTripper adds basic or bearer authentication to requests.
+type authRoundTripper struct {
+	auth pushAuth
+	next http.RoundTripper
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body must be closed on errors as well
+	fail := func(err error) (*http.Response, error) {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	switch {
+	case rt.auth.bearerToken != "" || rt.auth.bearerTokenFile != "":
+		token, err := readSecret(rt.auth.bearerToken, rt.auth.bearerTokenFile)
+		if err != nil {
+			return fail(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case rt.auth.username != "":
+		password, err := readSecret(rt.auth.password, rt.auth.passwordFile)
+		if err != nil {
+			return fail(err)
+		}
+		req.SetBasicAuth(rt.auth.username, password)
+	}
+	return rt.next.RoundTrip(req)
+}
+
+// newPushClient returns an HTTP client for pushing with auth.
+func newPushClient(auth pushAuth) *http.Client {
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &authRoundTripper{auth: auth, next: http.DefaultTransport},
+	}
+}
+
+// newPushgatewayPush returns a pushFunc that replaces the metrics of job on
+// the Pushgateway at url.
+func newPushgatewayPush(client *http.Client, url, job string) pushFunc {
+	return func(ctx context.Context, families []*dto.MetricFamily) error {
+		gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return families, nil
+		})
+		return push.New(url, job).Gatherer(gatherer).Client(client).PushContext(ctx)
+	}
+}
+
+// newRemoteWritePush returns a pushFunc that sends metric families to a
+// Prometheus remote-write endpoint at url.
+func newRemoteWritePush(client *http.Client, url string) pushFunc {
+	return func(ctx context.Context, families []*dto.MetricFamily) error {
+		body := s2.EncodeSnappy(nil, encodeWriteRequest(families, time.Now()))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		req.Header.Set("User-Agent", "tailscale-exporter/"+version)
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode/100 != 2 {
+			msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+			err := fmt.Errorf("remote write returned %s: %s", res.Status, bytes.TrimSpace(msg))
+			// The remote-write spec forbids retrying client errors, except for
+			// rate limiting
+			if res.StatusCode/100 == 4 && res.StatusCode != http.StatusTooManyRequests {
+				return permanentPushError{err: err}
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// pushWithRetry pushes families with fn until it succeeds, fails permanently
+// or retries are exhausted, waiting with exponential backoff between attempts.
+func pushWithRetry(
+	ctx context.Context,
+	logger *slog.Logger,
+	target string,
+	retries int,
+	families []*dto.MetricFamily,
+	fn pushFunc,
+) error {
+	backoff := pushRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx, families)
+		if err == nil {
+			return nil
+		}
+		pushFailures.WithLabelValues(target).Inc()
+		var permanentErr permanentPushError
+		if attempt >= retries || errors.As(err, &permanentErr) {
+			return err
+		}
+
+		logger.Warn("Push failed, retrying",
+			"target", target,
+			"attempt", attempt+1,
+			"backoff", backoff,
+			"err", err,
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// pushAll gathers the metrics once and pushes them to every target, logging
+// failures, and reports whether all pushes succeeded. Gathering runs all
+// collectors, so targets and retries share a single sweep of the API.
+func pushAll(
+	ctx context.Context,
+	logger *slog.Logger,
+	gatherer prometheus.Gatherer,
+	targets map[string]pushFunc,
+	retries int,
+) bool {
+	families, err := gatherer.Gather()
+	if err != nil {
+		logger.Error("Failed to gather metrics to push", "err", err)
+		for target := range targets {
+			pushFailures.WithLabelValues(target).Inc()
+		}
+		return false
+	}
+
+	ok := true
+	for target, fn := range targets {
+		if err := pushWithRetry(ctx, logger, target, retries, families, fn); err != nil {
+			logger.Error("Push failed", "target", target, "err", err)
+			ok = false
+			continue
+		}
+		logger.Debug("Push succeeded", "target", target)
+	}
+	return ok
+}
+
+// runPushLoop pushes the metrics of gatherer to every target each interval
+// until ctx is done.
+func runPushLoop(
+	ctx context.Context,
+	logger *slog.Logger,
+	gatherer prometheus.Gatherer,
+	targets map[string]pushFunc,
+	retries int,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pushAll(ctx, logger, gatherer, targets, retries)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Protobuf field numbers of the remote-write WriteRequest message and the
+// messages it contains.
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+type label struct{ name, value string }
+
+// encodeWriteRequest encodes metric families as a remote-write protobuf
+// WriteRequest, with all samples at now. Summaries and histograms are split
+// into their classic series.
+func encodeWriteRequest(families []*dto.MetricFamily, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+
+	var buf []byte
+	appendSeries := func(name string, labels []*dto.LabelPair, value float64, extra ...label) {
+		series := make([]label, 0, len(labels)+len(extra)+1)
+		series = append(series, label{"__name__", name})
+		for _, l := range labels {
+			series = append(series, label{l.GetName(), l.GetValue()})
+		}
+		series = append(series, extra...)
+		sort.Slice(series, func(i, j int) bool { return series[i].name < series[j].name })
+
+		var ts []byte
+		for _, l := range series {
+			var lb []byte
+			lb = protowire.AppendTag(lb, labelName, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, labelValue, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+			ts = protowire.AppendTag(ts, timeSeriesLabels, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+		var sb []byte
+		sb = protowire.AppendTag(sb, sampleValue, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(value))
+		sb = protowire.AppendTag(sb, sampleTimestamp, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(timestamp))
+		ts = protowire.AppendTag(ts, timeSeriesSamples, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sb)
+
+		buf = protowire.AppendTag(buf, writeRequestTimeseries, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, ts)
+	}
+
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			labels := m.GetLabel()
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				appendSeries(name, labels, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				appendSeries(name, labels, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				appendSeries(name, labels, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					appendSeries(name, labels, q.GetValue(),
+						label{"quantile", strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)})
+				}
+				appendSeries(name+"_sum", labels, summary.GetSampleSum())
+				appendSeries(name+"_count", labels, float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				histogram := m.GetHistogram()
+				hasInf := false
+				for _, b := range histogram.GetBucket() {
+					hasInf = math.IsInf(b.GetUpperBound(), 1)
+					appendSeries(name+"_bucket", labels, float64(b.GetCumulativeCount()),
+						label{"le", strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)})
+				}
+				if !hasInf {
+					appendSeries(name+"_bucket", labels, float64(histogram.GetSampleCount()),
+						label{"le", "+Inf"})
+				}
+				appendSeries(name+"_sum", labels, histogram.GetSampleSum())
+				appendSeries(name+"_count", labels, float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+	return buf
+}
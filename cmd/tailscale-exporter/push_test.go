@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodeWriteRequest decodes the label sets and sample values of a
+// remote-write WriteRequest.
+func decodeWriteRequest(t *testing.T, data []byte) ([]map[string]string, []float64) {
+	t.Helper()
+
+	fields := func(b []byte, fn func(num protowire.Number, v []byte, u uint64)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				fn(num, v, 0)
+				b = b[n:]
+			case protowire.Fixed64Type:
+				u, n := protowire.ConsumeFixed64(b)
+				fn(num, nil, u)
+				b = b[n:]
+			case protowire.VarintType:
+				u, n := protowire.ConsumeVarint(b)
+				fn(num, nil, u)
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %v", typ)
+			}
+		}
+	}
+
+	var labelSets []map[string]string
+	var values []float64
+	fields(data, func(_ protowire.Number, series []byte, _ uint64) {
+		labels := make(map[string]string)
+		fields(series, func(num protowire.Number, v []byte, _ uint64) {
+			switch num {
+			case timeSeriesLabels:
+				var name, value string
+				fields(v, func(num protowire.Number, v []byte, _ uint64) {
+					if num == labelName {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				labels[name] = value
+			case timeSeriesSamples:
+				fields(v, func(num protowire.Number, _ []byte, u uint64) {
+					if num == sampleValue {
+						values = append(values, math.Float64frombits(u))
+					}
+				})
+			}
+		})
+		labelSets = append(labelSets, labels)
+	})
+	return labelSets, values
+}
+
+func TestRemoteWritePush(t *testing.T) {
+	requests := 0
+	var labelSets []map[string]string
+	var values []float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			t.Errorf("expected basic auth, got %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("expected snappy encoding, got %q", r.Header.Get("Content-Encoding"))
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := s2.Decode(nil, body)
+		if err != nil {
+			t.Fatalf("decoding snappy: %v", err)
+		}
+		labelSets, values = decodeWriteRequest(t, data)
+	}))
+	defer server.Close()
+
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tailscale_up"}, []string{"tailnet"})
+	gauge.WithLabelValues("example.com").Set(1)
+	reg.MustRegister(gauge)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newPushClient(pushAuth{username: "user", password: "pass"})
+	fn := newRemoteWritePush(client, server.URL)
+
+	before := testutil.ToFloat64(pushFailures.WithLabelValues(pushTargetRemoteWrite))
+	if err := pushWithRetry(
+		context.Background(),
+		slog.Default(),
+		pushTargetRemoteWrite,
+		1,
+		families,
+		fn,
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failures := testutil.ToFloat64(pushFailures.WithLabelValues(pushTargetRemoteWrite)) - before; failures != 1 {
+		t.Errorf("expected 1 push failure, got %v", failures)
+	}
+
+	if len(labelSets) != 1 || len(values) != 1 {
+		t.Fatalf("expected 1 series, got %v %v", labelSets, values)
+	}
+	if labelSets[0]["__name__"] != "tailscale_up" || labelSets[0]["tailnet"] != "example.com" {
+		t.Errorf("unexpected labels %v", labelSets[0])
+	}
+	if values[0] != 1 {
+		t.Errorf("expected value 1, got %v", values[0])
+	}
+}
+
+func TestRemoteWritePush_Retries(t *testing.T) {
+	prevBackoff := pushRetryBackoff
+	pushRetryBackoff = time.Millisecond
+	t.Cleanup(func() { pushRetryBackoff = prevBackoff })
+
+	tests := []struct {
+		name             string
+		status           int
+		expectedRequests int
+	}{
+		{name: "client error", status: http.StatusBadRequest, expectedRequests: 1},
+		{name: "rate limited", status: http.StatusTooManyRequests, expectedRequests: 4},
+		{name: "server error", status: http.StatusServiceUnavailable, expectedRequests: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					http.Error(w, http.StatusText(tt.status), tt.status)
+				}),
+			)
+			defer server.Close()
+
+			fn := newRemoteWritePush(server.Client(), server.URL)
+			if err := pushWithRetry(
+				context.Background(),
+				slog.Default(),
+				pushTargetRemoteWrite,
+				3,
+				nil,
+				fn,
+			); err == nil {
+				t.Error("expected the push to fail")
+			}
+			if requests != tt.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tt.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestPushAll_GathersOnce(t *testing.T) {
+	prevBackoff := pushRetryBackoff
+	pushRetryBackoff = time.Millisecond
+	t.Cleanup(func() { pushRetryBackoff = prevBackoff })
+
+	gathers := 0
+	families := []*dto.MetricFamily{{Name: proto.String("tailscale_up")}}
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		gathers++
+		return families, nil
+	})
+
+	var pushed [][]*dto.MetricFamily
+	failures := 2
+	targets := map[string]pushFunc{
+		pushTargetRemoteWrite: func(_ context.Context, f []*dto.MetricFamily) error {
+			pushed = append(pushed, f)
+			if failures > 0 {
+				failures--
+				return errors.New("unavailable")
+			}
+			return nil
+		},
+		pushTargetPushgateway: func(_ context.Context, f []*dto.MetricFamily) error {
+			pushed = append(pushed, f)
+			return nil
+		},
+	}
+
+	if !pushAll(context.Background(), slog.Default(), gatherer, targets, 3) {
+		t.Error("expected all pushes to succeed")
+	}
+	if gathers != 1 {
+		t.Errorf("expected a single gather for all targets and retries, got %d", gathers)
+	}
+	if len(pushed) != 4 {
+		t.Fatalf("expected 4 push attempts, got %d", len(pushed))
+	}
+	for _, f := range pushed {
+		if len(f) != 1 || f[0] != families[0] {
+			t.Errorf("expected every attempt to push the gathered families, got %v", f)
+		}
+	}
+}
+
+func TestPushClient_Auth(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("pass\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		auth        pushAuth
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "basic auth",
+			auth:     pushAuth{username: "user", password: "pass"},
+			expected: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:     "basic auth password file",
+			auth:     pushAuth{username: "user", passwordFile: passwordFile},
+			expected: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:     "bearer token file",
+			auth:     pushAuth{bearerTokenFile: tokenFile},
+			expected: "Bearer token",
+		},
+		{
+			name:        "missing bearer token file",
+			auth:        pushAuth{bearerTokenFile: filepath.Join(dir, "missing")},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorization = ""
+			res, err := newPushClient(tt.auth).Get(server.URL)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = res.Body.Close()
+			if authorization != tt.expected {
+				t.Errorf("expected authorization %q, got %q", tt.expected, authorization)
+			}
+		})
+	}
+
+	if err := (pushAuth{bearerToken: "token", bearerTokenFile: tokenFile}).validate(); err == nil {
+		t.Error("expected an error when setting both the bearer token and its file")
+	}
+	if err := (pushAuth{password: "pass", passwordFile: passwordFile}).validate(); err == nil {
+		t.Error("expected an error when setting both the password and its file")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/oauth2"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+var (
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tailscale_exporter_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+	configReloadSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tailscale_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
+)
+
+// target is the collector of a tailnet, along with the token source of its
+// OAuth client.
+type target struct {
+	tailnet     string
+	collector   *collector.TailscaleCollector
+	tokenSource oauth2.TokenSource
+}
+
+// buildFunc creates the targets of the current configuration, carrying the
+// state of the previous targets over, by tailnet.
+type buildFunc func(prev map[string]*target) ([]*target, error)
+
+// exporter holds the targets of the current configuration and swaps them on
+// reload, so the listener and its handlers stay in place.
+type exporter struct {
+	logger *slog.Logger
+	build  buildFunc
+
+	// reloadMtx serializes reloads
+	reloadMtx sync.Mutex
+
+	mtx      sync.RWMutex
+	targets  []*target
+	registry *prometheus.Registry
+}
+
+// newExporter builds the initial targets, failing if the configuration is
+// invalid.
+func newExporter(logger *slog.Logger, build buildFunc) (*exporter, error) {
+	e := &exporter{logger: logger, build: build}
+	if err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// reload rebuilds the targets. On failure the previous targets keep serving.
+func (e *exporter) reload() error {
+	e.reloadMtx.Lock()
+	defer e.reloadMtx.Unlock()
+
+	err := e.apply()
+	if err != nil {
+		configReloadSuccess.Set(0)
+		return err
+	}
+	configReloadSuccess.Set(1)
+	configReloadSeconds.SetToCurrentTime()
+	return nil
+}
+
+func (e *exporter) apply() error {
+	prev := make(map[string]*target)
+	for _, t := range e.currentTargets() {
+		prev[t.tailnet] = t
+	}
+
+	targets, err := e.build(prev)
+	if err != nil {
+		return err
+	}
+
+	// Every tailnet gets its own registry view, labelling its metrics
+	registry := prometheus.NewRegistry()
+	for _, t := range targets {
+		reg := prometheus.WrapRegistererWith(prometheus.Labels{"tailnet": t.tailnet}, registry)
+		if err := reg.Register(t.collector); err != nil {
+			return fmt.Errorf("registering collector of tailnet %s: %w", t.tailnet, err)
+		}
+	}
+
+	e.mtx.Lock()
+	e.targets = targets
+	e.registry = registry
+	e.mtx.Unlock()
+	return nil
+}
+
+// currentTargets returns the targets of the current configuration.
+func (e *exporter) currentTargets() []*target {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.targets
+}
+
+// target returns the target of tailnet, which may be empty if there is only
+// one.
+func (e *exporter) target(tailnet string) (*target, error) {
+	targets := e.currentTargets()
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if t.tailnet == tailnet || (tailnet == "" && len(targets) == 1) {
+			return t, nil
+		}
+		names = append(names, t.tailnet)
+	}
+	if tailnet == "" {
+		return nil, fmt.Errorf("tailnet parameter is required, one of: %s",
+			strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("unknown tailnet %q, must be one of: %s",
+		tailnet, strings.Join(names, ", "))
+}
+
+// Gather implements prometheus.Gatherer, collecting the metrics of all
+// tailnets.
+func (e *exporter) Gather() ([]*dto.MetricFamily, error) {
+	e.mtx.RLock()
+	registry := e.registry
+	e.mtx.RUnlock()
+	return registry.Gather()
+}
+
+// policyGroups returns the policy file groups of tailnet. With a single
+// tailnet configured, its groups are returned whatever its name, as it may be
+// configured as "-".
+func (e *exporter) policyGroups(ctx context.Context, tailnet string) (map[string][]string, error) {
+	targets := e.currentTargets()
+	if len(targets) == 1 {
+		return targets[0].collector.PolicyGroups(ctx)
+	}
+	t, err := e.target(tailnet)
+	if err != nil {
+		return nil, err
+	}
+	return t.collector.PolicyGroups(ctx)
+}
+
+// InventoryHandler serves the inventory API of the tailnet selected by the
+// tailnet query parameter, which is optional with a single tailnet.
+func (e *exporter) InventoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		t, err := e.target(query.Get("tailnet"))
+		if err != nil {
+			writeHealthJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		// The inventory API rejects unknown parameters
+		query.Del("tailnet")
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = query.Encode()
+		t.collector.InventoryHandler().ServeHTTP(w, r)
+	})
+}
+
+// ReloadHandler reloads the configuration on POST requests. Unless enabled,
+// it rejects all requests, as anyone able to reach it could otherwise make
+// the exporter rebuild its collectors and request new OAuth tokens.
+func (e *exporter) ReloadHandler(enabled bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled {
+			writeHealthJSON(
+				w,
+				http.StatusForbidden,
+				map[string]string{
+					"error": "lifecycle API is not enabled, see --web.enable-lifecycle",
+				},
+			)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeHealthJSON(w, http.StatusMethodNotAllowed,
+				map[string]string{"error": "only POST requests are allowed"})
+			return
+		}
+
+		e.logger.Info("Reloading configuration", "source", "http")
+		if err := e.reload(); err != nil {
+			e.logger.Error("Error reloading configuration", "err", err)
+			writeHealthJSON(
+				w,
+				http.StatusInternalServerError,
+				map[string]string{"error": err.Error()},
+			)
+			return
+		}
+		e.logger.Info("Reloaded configuration")
+		writeHealthJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// reloadOnSIGHUP reloads the configuration on SIGHUP until ctx is done.
+func (e *exporter) reloadOnSIGHUP(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			e.logger.Info("Reloading configuration", "source", "signal")
+			if err := e.reload(); err != nil {
+				e.logger.Error("Error reloading configuration", "err", err)
+				continue
+			}
+			e.logger.Info("Reloaded configuration")
+		}
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 
 	"github.com/adinhodovic/tailscale-exporter/collector"
@@ -24,11 +27,50 @@ var (
 	// Global flags.
 	listenAddress string
 	metricsPath   string
+	webConfigFile string
+	configFile    string
+	whoisSocket   string
+	whoisAllow    []string
+	readyWindow   time.Duration
+	webLifecycle  bool
 	tailnet       string
 
+	// Log flags.
+	logLevel           string
+	logFormat          string
+	logCollectorLevels map[string]string
+
 	// OAuth flags.
 	oauthClientID     string
 	oauthClientSecret string
+
+	// Collector flags.
+	policyTestsFile     string
+	policyTestsInterval time.Duration
+	invitesInterval     time.Duration
+	aggregateOnly       bool
+	statePath           string
+	forbiddenBackoff    time.Duration
+	expiryWindows       []time.Duration
+
+	// OTLP flags.
+	otlpEndpoint string
+	otlpProtocol string
+	otlpHeaders  map[string]string
+	otlpInterval time.Duration
+
+	// Push flags.
+	pushRemoteWriteURL        string
+	pushPushgatewayURL        string
+	pushPushgatewayJob        string
+	pushInterval              time.Duration
+	pushOnce                  bool
+	pushRetries               int
+	pushBasicAuthUsername     string
+	pushBasicAuthPassword     string
+	pushBasicAuthPasswordFile string
+	pushBearerToken           string
+	pushBearerTokenFile       string
 )
 
 // rootCmd represents the base command when called without any subcommands.
@@ -57,15 +99,87 @@ func init() {
 		StringVarP(&listenAddress, "listen-address", "l", ":9250", "Address to listen on for web interface and telemetry")
 	rootCmd.PersistentFlags().
 		StringVarP(&metricsPath, "metrics-path", "m", "/metrics", "Path under which to expose metrics")
+	rootCmd.PersistentFlags().
+		StringVar(&webConfigFile, "web.config.file", "", "Path to a web configuration file enabling TLS or basic auth, see https://github.com/prometheus/exporter-toolkit/blob/master/docs/web-configuration.md")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&whoisAllow, "web.whois.allow", nil, "Tailnet users, group:<name> or tag:<name> allowed to read metrics and inventory, identified via the local tailscaled (disabled if empty)")
+	rootCmd.PersistentFlags().
+		StringVar(&whoisSocket, "web.whois.socket", "/var/run/tailscale/tailscaled.sock", "Path to the tailscaled LocalAPI socket used to identify clients")
+	rootCmd.PersistentFlags().
+		BoolVar(&webLifecycle, "web.enable-lifecycle", false, "Enable configuration reloads via HTTP POST to /-/reload")
+	rootCmd.PersistentFlags().
+		DurationVar(&readyWindow, "web.ready-window", 5*time.Minute, "Report ready only if a collector succeeded within this window")
 	rootCmd.PersistentFlags().
 		StringVarP(&tailnet, "tailnet", "t", "", "Tailscale tailnet (can also be set via TAILSCALE_TAILNET environment variable)")
 
+	// Configuration file, only used when serving metrics
+	rootCmd.Flags().
+		StringVar(&configFile, "config.file", "", "YAML file configuring tailnets, OAuth clients and collectors instead of the flags, reloaded on SIGHUP or a POST to /-/reload")
+
+	// Log flags
+	rootCmd.PersistentFlags().
+		StringVar(&logLevel, "log.level", "info", "Only log messages with the given severity or above, one of: debug, info, warn, error")
+	rootCmd.PersistentFlags().
+		StringVar(&logFormat, "log.format", logFormatLogfmt, "Output format of log messages, logfmt or json")
+	rootCmd.PersistentFlags().
+		StringToStringVar(&logCollectorLevels, "log.collector-level", nil, "Log level overrides per collector, e.g. devices=debug")
+
 	// Authentication flags - API Key or OAuth
 	rootCmd.PersistentFlags().
 		StringVar(&oauthClientID, "oauth-client-id", "", "OAuth client ID (can also be set via TAILSCALE_OAUTH_CLIENT_ID environment variable)")
 	rootCmd.PersistentFlags().
 		StringVar(&oauthClientSecret, "oauth-client-secret", "", "OAuth client secret (can also be set via TAILSCALE_OAUTH_CLIENT_SECRET environment variable)")
 
+	// Collector flags
+	rootCmd.PersistentFlags().
+		StringVar(&policyTestsFile, "policy.tests-file", "", "HuJSON file with policy tests to run instead of the tests in the policy file")
+	rootCmd.PersistentFlags().
+		DurationVar(&policyTestsInterval, "policy.tests-interval", collector.DefaultPolicyTestsInterval, "Minimum interval between policy test runs")
+	rootCmd.PersistentFlags().
+		DurationVar(&invitesInterval, "invites.device-interval", collector.DefaultDeviceInvitesInterval, "Minimum interval between listing the share invites of every device")
+	rootCmd.PersistentFlags().
+		BoolVar(&aggregateOnly, "aggregate-only", false, "Only export tailnet-level aggregates instead of per-device and per-user metrics")
+	rootCmd.PersistentFlags().
+		DurationVar(&forbiddenBackoff, "collector.forbidden-backoff", collector.DefaultForbiddenBackoff, "How long to disable a collector whose API rejects the credentials before retrying it")
+	rootCmd.PersistentFlags().
+		DurationSliceVar(&expiryWindows, "collector.expiry-windows", collector.DefaultExpiryWindows(), "Windows to count devices and keys expiring within")
+	rootCmd.PersistentFlags().
+		StringVar(&statePath, "state.path", "", "JSON file to persist device, user and settings inventory across restarts")
+
+	// OTLP flags
+	rootCmd.PersistentFlags().
+		StringVar(&otlpEndpoint, "otlp.endpoint", "", "OTLP endpoint URL to push metrics to, e.g. http://otel-collector:4317 (disabled if empty)")
+	rootCmd.PersistentFlags().
+		StringVar(&otlpProtocol, "otlp.protocol", "grpc", "OTLP protocol, grpc or http")
+	rootCmd.PersistentFlags().
+		StringToStringVar(&otlpHeaders, "otlp.headers", nil, "Headers to send with OTLP requests, e.g. authorization=Bearer <token>")
+	rootCmd.PersistentFlags().
+		DurationVar(&otlpInterval, "otlp.interval", time.Minute, "Interval between OTLP pushes")
+
+	// Push flags
+	rootCmd.PersistentFlags().
+		StringVar(&pushRemoteWriteURL, "push.remote-write-url", "", "Prometheus remote-write URL to push metrics to (disabled if empty)")
+	rootCmd.PersistentFlags().
+		StringVar(&pushPushgatewayURL, "push.pushgateway-url", "", "Pushgateway URL to push metrics to (disabled if empty)")
+	rootCmd.PersistentFlags().
+		StringVar(&pushPushgatewayJob, "push.pushgateway-job", "tailscale-exporter", "Job name to push metrics to the Pushgateway under")
+	rootCmd.PersistentFlags().
+		DurationVar(&pushInterval, "push.interval", time.Minute, "Interval between pushes")
+	rootCmd.PersistentFlags().
+		BoolVar(&pushOnce, "push.once", false, "Push metrics once and exit instead of serving them, e.g. when running as a CronJob")
+	rootCmd.PersistentFlags().
+		IntVar(&pushRetries, "push.retries", 3, "Number of retries of a failed push")
+	rootCmd.PersistentFlags().
+		StringVar(&pushBasicAuthUsername, "push.basic-auth-username", "", "Username for basic auth when pushing")
+	rootCmd.PersistentFlags().
+		StringVar(&pushBasicAuthPassword, "push.basic-auth-password", "", "Password for basic auth when pushing")
+	rootCmd.PersistentFlags().
+		StringVar(&pushBasicAuthPasswordFile, "push.basic-auth-password-file", "", "File with the password for basic auth when pushing, read on every push")
+	rootCmd.PersistentFlags().
+		StringVar(&pushBearerToken, "push.bearer-token", "", "Bearer token for authentication when pushing")
+	rootCmd.PersistentFlags().
+		StringVar(&pushBearerTokenFile, "push.bearer-token-file", "", "File with the bearer token for authentication when pushing, read on every push")
+
 	// Bind environment variables
 	if rootCmd.PersistentFlags().Lookup("tailnet").Value.String() == "" {
 		tailnet = getTailnetFromEnv()
@@ -83,9 +197,10 @@ func init() {
 }
 
 func runExporter(cmd *cobra.Command, args []string) error {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+	logger, err := newLogger(os.Stdout)
+	if err != nil {
+		return err
+	}
 
 	logger.Info("Starting tailscale_exporter",
 		"version", version,
@@ -96,75 +211,136 @@ func runExporter(cmd *cobra.Command, args []string) error {
 		"build_time", buildTime,
 	)
 
-	// Get tailnet from flag or environment
-	if tailnet == "" {
-		tailnet = getTailnetFromEnv()
+	// Fail fast on an invalid web configuration
+	if err := web.Validate(webConfigFile); err != nil {
+		return fmt.Errorf("invalid web configuration file: %w", err)
 	}
-	if tailnet == "" {
-		return errors.New(
-			"tailnet is required. Set via --tailnet flag or TAILSCALE_TAILNET environment variable",
+
+	// Collectors are built from the configuration file if set, or the flags,
+	// and rebuilt on reload
+	build := func(prev map[string]*target) ([]*target, error) {
+		cfg, err := loadConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.targets(logger, prev)
+	}
+	if configFile == "" {
+		build = func(prev map[string]*target) ([]*target, error) {
+			return flagTargets(logger, prev)
+		}
+	}
+	exp, err := newExporter(logger, build)
+	if err != nil {
+		return err
+	}
+	prometheus.MustRegister(configReloadSuccess, configReloadSeconds)
+
+	// Metrics of the exporter itself, followed by those of every tailnet
+	gatherer := prometheus.Gatherers{prometheus.DefaultGatherer, exp}
+
+	reloadCtx, cancelReload := context.WithCancel(context.Background())
+	defer cancelReload()
+	go exp.reloadOnSIGHUP(reloadCtx)
+
+	// Push metrics to Prometheus remote write or a Pushgateway
+	pushTargets := make(map[string]pushFunc)
+	auth := pushAuth{
+		username:        pushBasicAuthUsername,
+		password:        pushBasicAuthPassword,
+		passwordFile:    pushBasicAuthPasswordFile,
+		bearerToken:     pushBearerToken,
+		bearerTokenFile: pushBearerTokenFile,
+	}
+	if err := auth.validate(); err != nil {
+		return err
+	}
+	pushClient := newPushClient(auth)
+	if pushRemoteWriteURL != "" {
+		pushTargets[pushTargetRemoteWrite] = newRemoteWritePush(pushClient, pushRemoteWriteURL)
+	}
+	if pushPushgatewayURL != "" {
+		pushTargets[pushTargetPushgateway] = newPushgatewayPush(
+			pushClient, pushPushgatewayURL, pushPushgatewayJob,
 		)
 	}
-
-	logger.Info("Using tailnet", "tailnet", tailnet)
-
-	// Check if OAuth is requested or if OAuth credentials are provided
-	if oauthClientID == "" && oauthClientSecret == "" {
+	if pushOnce && len(pushTargets) == 0 {
 		return errors.New(
-			"authentication is required. Use OAuth with --oauth-client-id and --oauth-client-secret flags",
+			"--push.once requires --push.remote-write-url or --push.pushgateway-url",
 		)
 	}
-	oauthClientID = getOAuthClientIDFromEnv()
-	oauthClientSecret = getOAuthClientSecretFromEnv()
+	if len(pushTargets) > 0 {
+		prometheus.MustRegister(pushFailures)
+
+		if pushOnce {
+			if !pushAll(context.Background(), logger, gatherer, pushTargets, pushRetries) {
+				return errors.New("failed to push metrics")
+			}
+			logger.Info("Pushed metrics")
+			return nil
+		}
 
-	// Create OAuth client using client credentials flow
-	oauthConfig := &clientcredentials.Config{
-		ClientID:     oauthClientID,
-		ClientSecret: oauthClientSecret,
-		TokenURL:     "https://api.tailscale.com/api/v2/oauth/token",
-		Scopes: []string{
-			"devices:read",
-			"devices:routes:read",
-			"users:read",
-			"dns:read",
-			"auth_keys:read",
-			"feature_settings:read",
-			"policy_file:read",
-		}, // Request needed scopes
+		pushCtx, cancelPush := context.WithCancel(context.Background())
+		defer cancelPush()
+		go runPushLoop(pushCtx, logger, gatherer, pushTargets, pushRetries, pushInterval)
+		logger.Info("Pushing metrics", "interval", pushInterval)
 	}
 
-	// Create HTTP client that automatically handles token refresh
-	httpClient := oauthConfig.Client(context.Background())
-
-	// Test OAuth token generation
-	token, err := oauthConfig.Token(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to obtain OAuth token: %w", err)
+	// Push metrics to an OTLP endpoint in addition to serving them
+	if otlpEndpoint != "" {
+		shutdownOTLP, err := startOTLPPush(
+			context.Background(),
+			gatherer,
+			otlpProtocol,
+			otlpEndpoint,
+			otlpHeaders,
+			otlpInterval,
+		)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := shutdownOTLP(ctx); err != nil {
+				logger.Error("OTLP shutdown error", "err", err)
+			}
+		}()
+		logger.Info("Pushing metrics via OTLP",
+			"endpoint", otlpEndpoint,
+			"protocol", otlpProtocol,
+			"interval", otlpInterval,
+		)
 	}
-	logger.Info("OAuth token obtained", "token_type", token.TokenType)
-	logger.Info("Successfully obtained OAuth token", "expires", token.Expiry)
-
-	// Default labels for all metrics
-	defaultLabels := prometheus.Labels{"tailnet": tailnet}
-	reg := prometheus.WrapRegistererWith(
-		defaultLabels,
-		prometheus.DefaultRegisterer,
-	)
 
-	// Create collector with OAuth HTTP client
-	tsCollector, err := collector.NewTailscaleCollector(
-		logger,
-		httpClient,
-		tailnet,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create Tailscale collector: %w", err)
+	// Restrict metrics and inventory to allowed tailnet identities
+	protect := func(h http.Handler) http.Handler { return h }
+	if len(whoisAllow) > 0 {
+		authorizer := newWhoisAuthorizer(logger, whoisSocket, whoisAllow, exp.policyGroups)
+		protect = authorizer.Wrap
+		logger.Info("Restricting access by tailnet identity", "allow", whoisAllow)
 	}
 
-	reg.MustRegister(tsCollector)
-
 	// Create HTTP server
-	http.Handle(metricsPath, promhttp.Handler())
+	http.Handle(metricsPath, protect(promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+	)))
+
+	// Inventory API serving the latest collected data
+	apiPath := path.Join(path.Dir(metricsPath), "api/v1")
+	http.Handle(apiPath+"/", protect(http.StripPrefix(apiPath, exp.InventoryHandler())))
+
+	// Health endpoints for probes, not restricted by tailnet identity
+	http.HandleFunc("/-/healthy", healthyHandler)
+	http.Handle("/-/ready", &readyHandler{
+		logger:  logger,
+		targets: exp.currentTargets,
+		window:  readyWindow,
+	})
+
+	// Reload the configuration without dropping the listener
+	http.Handle("/-/reload", protect(exp.ReloadHandler(webLifecycle)))
 
 	// Root handler with simple landing page
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -174,6 +350,10 @@ func runExporter(cmd *cobra.Command, args []string) error {
 			<body>
 			<h1>Tailscale Exporter</h1>
 			<p><a href='` + metricsPath + `'>Metrics</a></p>
+			<p><a href='` + apiPath + `/devices'>Devices</a></p>
+			<p><a href='` + apiPath + `/users'>Users</a></p>
+			<p><a href='` + apiPath + `/keys'>Keys</a></p>
+			<p><a href='` + apiPath + `/settings'>Settings</a></p>
 			</body>
 			</html>`))
 		if err != nil {
@@ -203,8 +383,7 @@ func runExporter(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	logger.Info("Listening", "address", listenAddress)
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	if err := listenAndServe(server, logger); err != http.ErrServerClosed {
 		return fmt.Errorf("HTTP server failed: %w", err)
 	}
 
@@ -212,6 +391,133 @@ func runExporter(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// listenAndServe serves server on the listen address, enabling TLS and basic
+// auth as set in the web configuration file. The file is re-read on every
+// request and TLS connection, so changes to it apply without a restart. Basic
+// auth covers every endpoint, including the health endpoints.
+func listenAndServe(server *http.Server, logger *slog.Logger) error {
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &[]string{listenAddress},
+		WebSystemdSocket:   new(bool),
+		WebConfigFile:      &webConfigFile,
+	}
+	return web.ListenAndServe(server, webFlags, logger)
+}
+
+// newOAuthHTTPClient validates the tailnet and OAuth flags, obtains a token
+// and returns an HTTP client that refreshes it as needed, along with its
+// token source.
+func newOAuthHTTPClient(logger *slog.Logger) (*http.Client, oauth2.TokenSource, error) {
+	// Get tailnet from flag or environment
+	if tailnet == "" {
+		tailnet = getTailnetFromEnv()
+	}
+	if tailnet == "" {
+		return nil, nil, errors.New(
+			"tailnet is required. Set via --tailnet flag or TAILSCALE_TAILNET environment variable",
+		)
+	}
+
+	logger.Info("Using tailnet", "tailnet", tailnet)
+
+	// Check if OAuth is requested or if OAuth credentials are provided
+	if oauthClientID == "" && oauthClientSecret == "" {
+		return nil, nil, errors.New(
+			"authentication is required. Use OAuth with --oauth-client-id and --oauth-client-secret flags",
+		)
+	}
+	oauthClientID = getOAuthClientIDFromEnv()
+	oauthClientSecret = getOAuthClientSecretFromEnv()
+
+	return oauthHTTPClient(logger, oauthClientID, oauthClientSecret)
+}
+
+// oauthTokenURL is the Tailscale OAuth token endpoint.
+var oauthTokenURL = "https://api.tailscale.com/api/v2/oauth/token"
+
+// oauthHTTPClient obtains a token with the OAuth client credentials and
+// returns an HTTP client that refreshes it as needed, along with its token
+// source.
+func oauthHTTPClient(
+	logger *slog.Logger,
+	clientID, clientSecret string,
+) (*http.Client, oauth2.TokenSource, error) {
+	// Create OAuth client using client credentials flow. No scopes are
+	// requested, as the token endpoint rejects scopes the client was not
+	// granted. The token gets all scopes of the client instead, and
+	// collectors whose API is not covered are disabled on their first 403.
+	oauthConfig := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     oauthTokenURL,
+	}
+
+	// Create HTTP client that automatically handles token refresh
+	tokenSource := oauthConfig.TokenSource(context.Background())
+	httpClient := oauth2.NewClient(context.Background(), tokenSource)
+
+	// Test OAuth token generation
+	token, err := tokenSource.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to obtain OAuth token: %w", err)
+	}
+	logger.Info("OAuth token obtained", "token_type", token.TokenType)
+	logger.Info("Successfully obtained OAuth token", "expires", token.Expiry)
+
+	return httpClient, tokenSource, nil
+}
+
+// flagTargets creates the collector of the tailnet configured by the flags,
+// carrying the state of its collector in prev over.
+func flagTargets(logger *slog.Logger, prev map[string]*target) ([]*target, error) {
+	httpClient, tokenSource, err := newOAuthHTTPClient(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []collector.Option
+	if p, ok := prev[tailnet]; ok {
+		opts = append(opts, collector.WithPrevious(p.collector))
+	}
+	tsCollector, err := newTailscaleCollector(logger, httpClient, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return []*target{{tailnet: tailnet, collector: tsCollector, tokenSource: tokenSource}}, nil
+}
+
+// newTailscaleCollector creates the Tailscale collector configured by the
+// collector flags, plus opts.
+func newTailscaleCollector(
+	logger *slog.Logger,
+	httpClient *http.Client,
+	opts ...collector.Option,
+) (*collector.TailscaleCollector, error) {
+	logLevels, err := collectorLogLevels()
+	if err != nil {
+		return nil, err
+	}
+
+	tsCollector, err := collector.NewTailscaleCollector(
+		logger,
+		httpClient,
+		tailnet,
+		append([]collector.Option{
+			collector.WithPolicyTests(policyTestsFile, policyTestsInterval),
+			collector.WithDeviceInvitesInterval(invitesInterval),
+			collector.WithAggregateOnly(aggregateOnly),
+			collector.WithStatePath(statePath),
+			collector.WithForbiddenBackoff(forbiddenBackoff),
+			collector.WithLogLevels(logLevels),
+			collector.WithExpiryWindows(expiryWindows...),
+		}, opts...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Tailscale collector: %w", err)
+	}
+	return tsCollector, nil
+}
+
 // SetVersionInfo sets the version information for the command.
 func SetVersionInfo(v, c, bt string) {
 	version = v
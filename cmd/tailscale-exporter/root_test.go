@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/adinhodovic/tailscale-exporter/collector"
+)
+
+// rewriteTransport sends all requests to the server at target.
+type rewriteTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return rt.base.RoundTrip(req)
+}
+
+func TestOAuthHTTPClient_MissingScope(t *testing.T) {
+	// The OAuth client was granted devices:read only. Like Tailscale, the
+	// token endpoint rejects requests for scopes that were not granted.
+	granted := "devices:read"
+	tokenServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("parsing token request: %v", err)
+			}
+			for _, scope := range strings.Fields(r.PostForm.Get("scope")) {
+				if scope != granted {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":"invalid_scope"}`))
+					return
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(
+				`{"access_token":"token","token_type":"Bearer","expires_in":3600,"scope":"` +
+					granted + `"}`,
+			))
+		}),
+	)
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/keys") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(
+				`{"message":"calling actor does not have enough permissions to perform this function"}`,
+			))
+			return
+		}
+		_, _ = w.Write([]byte(`{"devices":[]}`))
+	}))
+	defer apiServer.Close()
+
+	prevTokenURL := oauthTokenURL
+	oauthTokenURL = tokenServer.URL
+	t.Cleanup(func() { oauthTokenURL = prevTokenURL })
+
+	httpClient, _, err := oauthHTTPClient(slog.Default(), "id", "secret")
+	if err != nil {
+		t.Fatalf("expected a token for the granted scopes, got %v", err)
+	}
+
+	target, err := url.Parse(apiServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient.Transport = rewriteTransport{target: target, base: httpClient.Transport}
+
+	tsCollector, err := collector.NewTailscaleCollector(
+		slog.Default(),
+		httpClient,
+		"example.com",
+		collector.WithCollectors("devices", "keys"),
+	)
+	if err != nil {
+		t.Fatalf("creating collector: %v", err)
+	}
+
+	expected := `
+# HELP tailscale_exporter_collector_enabled tailscale_exporter: Whether a collector is enabled, or the reason it was disabled.
+# TYPE tailscale_exporter_collector_enabled gauge
+tailscale_exporter_collector_enabled{collector="devices",reason=""} 1
+tailscale_exporter_collector_enabled{collector="keys",reason="forbidden"} 0
+`
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(tsCollector)
+	if err := testutil.GatherAndCompare(
+		registry,
+		strings.NewReader(expected),
+		"tailscale_exporter_collector_enabled",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListenAndServe_BasicAuth(t *testing.T) {
+	webConfig := filepath.Join(t.TempDir(), "web.yml")
+	if err := os.WriteFile(webConfig, []byte(`basic_auth_users:
+  prometheus: $2a$04$tLc6Mscc2uEO3kvFtvNZa.653eUajOgg7YlzOQpTESQLRZrZ8qeSO # bcrypt hash of "secret"
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	prevListenAddress, prevWebConfigFile := listenAddress, webConfigFile
+	listenAddress, webConfigFile = addr, webConfig
+	t.Cleanup(func() { listenAddress, webConfigFile = prevListenAddress, prevWebConfigFile })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/-/healthy", healthyHandler)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {})
+	server := &http.Server{Handler: mux}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- listenAndServe(server, slog.Default()) }()
+	t.Cleanup(func() {
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("unexpected server error: %v", err)
+		}
+	})
+
+	get := func(path, username, password string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		for range 50 {
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				// The server may not be listening yet
+				time.Sleep(20 * time.Millisecond)
+				continue
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		t.Fatalf("server did not start listening on %s", addr)
+		return 0
+	}
+
+	tests := []struct {
+		path, username, password string
+		expected                 int
+	}{
+		{"/metrics", "", "", http.StatusUnauthorized},
+		{"/metrics", "prometheus", "wrong", http.StatusUnauthorized},
+		{"/metrics", "prometheus", "secret", http.StatusOK},
+		// The probes need credentials too, see the Helm chart values
+		{"/-/healthy", "", "", http.StatusUnauthorized},
+		{"/-/healthy", "prometheus", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := get(tt.path, tt.username, tt.password); code != tt.expected {
+			t.Errorf("GET %s as %q: expected %d, got %d", tt.path, tt.username, tt.expected, code)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// whoisGroupsTTL is how long group members from the policy file are
+	// cached for.
+	whoisGroupsTTL = time.Minute
+
+	// localAPIHost is the host name tailscaled expects on LocalAPI requests
+	// over its unix socket.
+	localAPIHost = "local-tailscaled.sock"
+)
+
+// whoisResponse is the subset of the LocalAPI whois response the allow-list
+// needs.
+type whoisResponse struct {
+	Node struct {
+		Name string   `json:"Name"`
+		Tags []string `json:"Tags"`
+	} `json:"Node"`
+	UserProfile struct {
+		LoginName string `json:"LoginName"`
+	} `json:"UserProfile"`
+}
+
+// statusResponse is the subset of the LocalAPI status response that tells
+// which tailnet the local node belongs to.
+type statusResponse struct {
+	CurrentTailnet *tailnetStatus `json:"CurrentTailnet"`
+}
+
+// tailnetStatus is the subset of the tailnet of the local node in the
+// LocalAPI status response.
+type tailnetStatus struct {
+	Name string `json:"Name"`
+}
+
+// whoisAuthorizer allows requests from tailnet users, members of policy file
+// groups or tagged nodes on an allow-list, identified by asking the local
+// tailscaled who the remote address belongs to.
+type whoisAuthorizer struct {
+	logger *slog.Logger
+	client *http.Client
+
+	users  []string
+	groups []string
+	tags   []string
+
+	// groupMembers returns the members of the policy file groups of tailnet.
+	groupMembers func(ctx context.Context, tailnet string) (map[string][]string, error)
+
+	mtx           sync.Mutex
+	groupsCache   map[string][]string
+	groupsFetched time.Time
+}
+
+// newWhoisAuthorizer creates an authorizer that talks to tailscaled on
+// socket. Entries of allow starting with group: or tag: match groups and
+// tags, others match login names. Groups are those of the tailnet the local
+// node belongs to, as every client reaches the exporter through it.
+func newWhoisAuthorizer(
+	logger *slog.Logger,
+	socket string,
+	allow []string,
+	groupMembers func(ctx context.Context, tailnet string) (map[string][]string, error),
+) *whoisAuthorizer {
+	a := &whoisAuthorizer{
+		logger: logger,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+		groupMembers: groupMembers,
+	}
+	for _, entry := range allow {
+		switch {
+		case strings.HasPrefix(entry, "group:"):
+			a.groups = append(a.groups, entry)
+		case strings.HasPrefix(entry, "tag:"):
+			a.tags = append(a.tags, entry)
+		default:
+			a.users = append(a.users, entry)
+		}
+	}
+	return a
+}
+
+// Wrap returns a handler that only passes allowed requests on to next, and
+// responds with 403 Forbidden otherwise.
+func (a *whoisAuthorizer) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		who, err := a.whois(r.Context(), r.RemoteAddr)
+		if err != nil {
+			a.logger.Warn("Error identifying client", "remote_addr", r.RemoteAddr, "err", err)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if !a.allowed(r.Context(), who) {
+			a.logger.Info("Denying request",
+				"remote_addr", r.RemoteAddr,
+				"user", who.UserProfile.LoginName,
+				"node", who.Node.Name,
+				"tags", who.Node.Tags,
+			)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// whois asks tailscaled which node and user remoteAddr belongs to.
+func (a *whoisAuthorizer) whois(ctx context.Context, remoteAddr string) (*whoisResponse, error) {
+	var who whoisResponse
+	if err := a.localAPI(ctx, "whois", url.Values{"addr": {remoteAddr}}, &who); err != nil {
+		return nil, err
+	}
+	return &who, nil
+}
+
+// localTailnet asks tailscaled which tailnet the local node belongs to.
+func (a *whoisAuthorizer) localTailnet(ctx context.Context) (string, error) {
+	var status statusResponse
+	if err := a.localAPI(ctx, "status", url.Values{"peers": {"false"}}, &status); err != nil {
+		return "", err
+	}
+	if status.CurrentTailnet == nil {
+		return "", errors.New("local node is not logged in to a tailnet")
+	}
+	return status.CurrentTailnet.Name, nil
+}
+
+// localAPI fetches the LocalAPI endpoint and decodes its JSON response into
+// out.
+func (a *whoisAuthorizer) localAPI(
+	ctx context.Context,
+	endpoint string,
+	query url.Values,
+	out any,
+) error {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     localAPIHost,
+		Path:     "/localapi/v0/" + endpoint,
+		RawQuery: query.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (a *whoisAuthorizer) allowed(ctx context.Context, who *whoisResponse) bool {
+	// Login names are case-insensitive, like in the policy file
+	login := who.UserProfile.LoginName
+	matchesLogin := func(name string) bool { return strings.EqualFold(name, login) }
+	if slices.ContainsFunc(a.users, matchesLogin) {
+		return true
+	}
+	for _, tag := range who.Node.Tags {
+		if slices.Contains(a.tags, tag) {
+			return true
+		}
+	}
+	if len(a.groups) == 0 || len(who.Node.Tags) > 0 {
+		return false
+	}
+
+	members, err := a.policyGroups(ctx)
+	if err != nil {
+		a.logger.Error("Error getting policy file groups", "err", err)
+		return false
+	}
+	for _, group := range a.groups {
+		if slices.ContainsFunc(members[group], matchesLogin) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyGroups returns the members of the policy file groups of the local
+// tailnet, cached for whoisGroupsTTL.
+func (a *whoisAuthorizer) policyGroups(ctx context.Context) (map[string][]string, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if !a.groupsFetched.IsZero() && time.Since(a.groupsFetched) < whoisGroupsTTL {
+		return a.groupsCache, nil
+	}
+	tailnet, err := a.localTailnet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the local tailnet: %w", err)
+	}
+	groups, err := a.groupMembers(ctx, tailnet)
+	if err != nil {
+		return nil, err
+	}
+	a.groupsCache = groups
+	a.groupsFetched = time.Now()
+	return groups, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// fakeLocalAPI serves the LocalAPI whois and status endpoints on a unix
+// socket, answering with the identity registered for the requested address
+// and the local tailnet.
+func fakeLocalAPI(t *testing.T, tailnet string, identities map[string]whoisResponse) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "tailscaled.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listening on unix socket: %v", err)
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/localapi/v0/status" {
+			status := statusResponse{CurrentTailnet: &tailnetStatus{Name: tailnet}}
+			if err := json.NewEncoder(w).Encode(status); err != nil {
+				t.Errorf("encoding status response: %v", err)
+			}
+			return
+		}
+		if r.URL.Path != "/localapi/v0/whois" {
+			http.NotFound(w, r)
+			return
+		}
+		who, ok := identities[r.URL.Query().Get("addr")]
+		if !ok {
+			http.Error(w, "no match for IP:port", http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(who); err != nil {
+			t.Errorf("encoding whois response: %v", err)
+		}
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return socket
+}
+
+func identity(login string, tags ...string) whoisResponse {
+	var who whoisResponse
+	who.UserProfile.LoginName = login
+	who.Node.Name = "node.example.ts.net."
+	who.Node.Tags = tags
+	return who
+}
+
+func TestWhoisAuthorizer(t *testing.T) {
+	socket := fakeLocalAPI(t, "example.com", map[string]whoisResponse{
+		"100.64.0.1:1001": identity("Alice@Example.com"),
+		"100.64.0.2:1002": identity("bob@example.com"),
+		"100.64.0.3:1003": identity("tagged-devices", "tag:monitoring"),
+		"100.64.0.4:1004": identity("tagged-devices", "tag:web"),
+		"100.64.0.5:1005": identity("mallory@example.com"),
+		"100.64.0.6:1006": identity("BOB@example.com"),
+		"100.64.0.7:1007": identity("eve@example.org"),
+	})
+
+	// group:sre of another monitored tailnet must not grant access
+	groupMembers := func(_ context.Context, tailnet string) (map[string][]string, error) {
+		if tailnet != "example.com" {
+			return map[string][]string{"group:sre": {"eve@example.org"}}, nil
+		}
+		return map[string][]string{
+			"group:sre": {"bob@example.com"},
+		}, nil
+	}
+	authorizer := newWhoisAuthorizer(
+		slog.Default(),
+		socket,
+		[]string{"alice@example.com", "group:sre", "tag:monitoring"},
+		groupMembers,
+	)
+	handler := authorizer.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		expected   int
+	}{
+		{
+			name:       "allowed user in other case",
+			remoteAddr: "100.64.0.1:1001",
+			expected:   http.StatusOK,
+		},
+		{name: "allowed group member", remoteAddr: "100.64.0.2:1002", expected: http.StatusOK},
+		{
+			name:       "allowed group member in other case",
+			remoteAddr: "100.64.0.6:1006",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "group member of other tailnet",
+			remoteAddr: "100.64.0.7:1007",
+			expected:   http.StatusForbidden,
+		},
+		{name: "allowed tag", remoteAddr: "100.64.0.3:1003", expected: http.StatusOK},
+		{name: "other tag", remoteAddr: "100.64.0.4:1004", expected: http.StatusForbidden},
+		{name: "other user", remoteAddr: "100.64.0.5:1005", expected: http.StatusForbidden},
+		{name: "not in tailnet", remoteAddr: "192.0.2.1:1234", expected: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
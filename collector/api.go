@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var defaultAPIBaseURL = &url.URL{Scheme: "https", Host: "api.tailscale.com"}
+
+// apiClient performs requests against Tailscale API endpoints that the
+// tailscale client library does not cover yet.
+type apiClient struct {
+	http    *http.Client
+	baseURL *url.URL
+	tailnet string
+}
+
+// apiError is returned for API responses with an error status, formatted the
+// same way as tailscale.APIError.
+type apiError struct {
+	Message string `json:"message"`
+	status  int
+}
+
+func (err apiError) Error() string {
+	return fmt.Sprintf("%s (%v)", err.Message, err.status)
+}
+
+// apiErrorTransport replaces the bodies of error responses that are not API
+// errors, such as the HTML pages of proxies, with an API error. Otherwise the
+// tailscale client library fails to decode them and the status is lost.
+type apiErrorTransport struct {
+	next http.RoundTripper
+}
+
+// withAPIErrors returns a copy of client that sends its requests through an
+// apiErrorTransport.
+func withAPIErrors(client *http.Client) *http.Client {
+	wrapped := *client
+	next := wrapped.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped.Transport = apiErrorTransport{next: next}
+	return &wrapped
+}
+
+func (t apiErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.StatusCode < http.StatusBadRequest {
+		return res, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if json.Unmarshal(body, &apiError{}) != nil {
+		body, err = json.Marshal(apiError{Message: http.StatusText(res.StatusCode)})
+		if err != nil {
+			return nil, err
+		}
+		res.Header = res.Header.Clone()
+		res.Header.Set("Content-Type", "application/json")
+		res.Header.Del("Content-Length")
+		res.ContentLength = int64(len(body))
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// getTailnet fetches /api/v2/tailnet/<tailnet>/<pathElements> and decodes the
+// JSON response into out.
+func (c *apiClient) getTailnet(ctx context.Context, out any, pathElements ...string) error {
+	return c.get(ctx, out, append([]string{"tailnet", c.tailnet}, pathElements...)...)
+}
+
+// get fetches /api/v2/<pathElements> and decodes the JSON response into out.
+func (c *apiClient) get(ctx context.Context, out any, pathElements ...string) error {
+	return c.do(ctx, http.MethodGet, nil, "", out, pathElements...)
+}
+
+// postTailnet posts body with contentType to
+// /api/v2/tailnet/<tailnet>/<pathElements> and decodes the JSON response into
+// out.
+func (c *apiClient) postTailnet(
+	ctx context.Context,
+	body io.Reader,
+	contentType string,
+	out any,
+	pathElements ...string,
+) error {
+	return c.do(
+		ctx,
+		http.MethodPost,
+		body,
+		contentType,
+		out,
+		append([]string{"tailnet", c.tailnet}, pathElements...)...,
+	)
+}
+
+// do sends a request to /api/v2/<pathElements> and decodes the JSON response
+// into out.
+func (c *apiClient) do(
+	ctx context.Context,
+	method string,
+	reqBody io.Reader,
+	contentType string,
+	out any,
+	pathElements ...string,
+) error {
+	elem := []string{"api", "v2"}
+	for _, pathElement := range pathElements {
+		elem = append(elem, url.PathEscape(pathElement))
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		method,
+		c.baseURL.JoinPath(elem...).String(),
+		reqBody,
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		apiErr := apiError{status: res.StatusCode}
+		if err := json.Unmarshal(body, &apiErr); err != nil {
+			apiErr.Message = http.StatusText(res.StatusCode)
+		}
+		return apiErr
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// apiID is an identifier that the API returns either as a JSON string or as
+// a JSON number.
+type apiID string
+
+func (id *apiID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	*id = apiID(strings.Trim(string(data), `"`))
+	return nil
+}
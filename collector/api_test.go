@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleClientWrapper_DNSConfiguration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/tailnet/example.com/dns/configuration" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"forbidden"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"preferences":{"overrideLocalDNS":true,"magicDNS":true}}`))
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		tailnet     string
+		expectError string
+	}{
+		{
+			name:    "successful request",
+			tailnet: "example.com",
+		},
+		{
+			name:        "api error",
+			tailnet:     "other.com",
+			expectError: "forbidden (403)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewTailscaleClientWrapper(&tailscale.Client{
+				BaseURL: baseURL,
+				HTTP:    server.Client(),
+				Tailnet: tt.tailnet,
+			})
+
+			configuration, err := client.DNS().Configuration(context.Background())
+			if tt.expectError != "" {
+				if err == nil || err.Error() != tt.expectError {
+					t.Errorf("expected error %q but got %v", tt.expectError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !configuration.Preferences.OverrideLocalDNS {
+				t.Errorf("expected override local DNS to be enabled")
+			}
+		})
+	}
+}
+
+func TestTailscaleClientWrapper_PolicyFileValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost ||
+			r.URL.Path != "/api/v2/tailnet/example.com/acl/validate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/hujson" {
+			t.Errorf("expected a HuJSON request, got %q", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case "failing":
+			_, _ = w.Write([]byte(
+				`{"message":"test(s) failed","data":[{"user":"bob@example.com","errors":["expected accept"]}]}`,
+			))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message":"internal error"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewTailscaleClientWrapper(&tailscale.Client{
+		BaseURL: baseURL,
+		HTTP:    server.Client(),
+		Tailnet: "example.com",
+	})
+
+	tests := []struct {
+		name              string
+		body              string
+		expectError       bool
+		validationFailure bool
+	}{
+		{name: "valid", body: "passing"},
+		{name: "rejected", body: "failing", expectError: true, validationFailure: true},
+		{name: "api error", body: "broken", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.PolicyFile().Validate(context.Background(), tt.body)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if err == nil {
+				return
+			}
+			if got := isPolicyValidationFailure(err); got != tt.validationFailure {
+				t.Errorf(
+					"expected validation failure %v, got %v (%v)",
+					tt.validationFailure,
+					got,
+					err,
+				)
+			}
+			var validationErr policyValidationError
+			if tt.validationFailure && errors.As(err, &validationErr) &&
+				validationErr.Data[0].User != "bob@example.com" {
+				t.Errorf("expected the failing user, got %v", validationErr.Data)
+			}
+		})
+	}
+}
+
+func TestAPIErrorTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/tailnet/example.com/devices":
+			// A proxy in front of the API
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html><body><h1>502 Bad Gateway</h1></body></html>"))
+		case "/api/v2/tailnet/example.com/keys":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"calling actor does not have enough permissions"}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte("rate limited"))
+		}
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &tailscale.Client{
+		BaseURL: baseURL,
+		HTTP:    withAPIErrors(server.Client()),
+		Tailnet: "example.com",
+	}
+
+	tests := []struct {
+		name          string
+		call          func(ctx context.Context) error
+		expectedKind  string
+		expectedError string
+	}{
+		{
+			name: "non-JSON server error",
+			call: func(ctx context.Context) error {
+				_, err := client.Devices().List(ctx)
+				return err
+			},
+			expectedKind:  errorKindServerError,
+			expectedError: "Bad Gateway (502)",
+		},
+		{
+			name: "API error",
+			call: func(ctx context.Context) error {
+				_, err := client.Keys().List(ctx, false)
+				return err
+			},
+			expectedKind:  errorKindForbidden,
+			expectedError: "calling actor does not have enough permissions (403)",
+		},
+		{
+			name: "non-JSON rate limit",
+			call: func(ctx context.Context) error {
+				_, err := client.Users().List(ctx, nil, nil)
+				return err
+			},
+			expectedKind:  errorKindRateLimited,
+			expectedError: "Too Many Requests (429)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(context.Background())
+			if err == nil || err.Error() != tt.expectedError {
+				t.Errorf("expected error %q, got %v", tt.expectedError, err)
+			}
+			if kind := errorKind(err); kind != tt.expectedKind {
+				t.Errorf("expected %s, got %s", tt.expectedKind, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+)
+
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+)
+
+// changeTracker keeps the inventory of the previous poll, so collectors can
+// count entries that were added, removed or changed between polls.
+type changeTracker[T comparable] struct {
+	mtx      sync.Mutex
+	previous map[string]T
+	counts   map[string]float64
+}
+
+// observe compares current with the inventory of the previous poll and
+// returns the number of changes seen so far, by kind. diff is called for
+// every entry that was added (prev is nil), removed (cur is nil) or changed,
+// and returns the kinds of change to count. Nothing is counted on the first
+// poll, as there is nothing to compare with yet.
+func (t *changeTracker[T]) observe(
+	current map[string]T,
+	diff func(id string, prev, cur *T) []string,
+) map[string]float64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.counts == nil {
+		t.counts = make(map[string]float64)
+	}
+
+	if t.previous != nil {
+		for id, cur := range current {
+			prev, ok := t.previous[id]
+			switch {
+			case !ok:
+				t.count(diff(id, nil, &cur))
+			case prev != cur:
+				t.count(diff(id, &prev, &cur))
+			}
+		}
+		for id, prev := range t.previous {
+			if _, ok := current[id]; !ok {
+				t.count(diff(id, &prev, nil))
+			}
+		}
+	}
+
+	t.previous = current
+	return maps.Clone(t.counts)
+}
+
+func (t *changeTracker[T]) count(kinds []string) {
+	for _, kind := range kinds {
+		t.counts[kind]++
+	}
+}
+
+// changeTrackerState is the persisted form of a changeTracker.
+type changeTrackerState[T comparable] struct {
+	Previous map[string]T       `json:"previous"`
+	Counts   map[string]float64 `json:"counts"`
+}
+
+// state returns a snapshot of the tracker for the state store.
+func (t *changeTracker[T]) state() changeTrackerState[T] {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return changeTrackerState[T]{
+		Previous: maps.Clone(t.previous),
+		Counts:   maps.Clone(t.counts),
+	}
+}
+
+// restore replaces the tracker with a snapshot from the state store, so the
+// next poll is compared with the inventory seen before the restart.
+func (t *changeTracker[T]) restore(state changeTrackerState[T]) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.previous = state.Previous
+	t.counts = state.Counts
+}
+
+// saveState encodes the tracker for the state store.
+func (t *changeTracker[T]) saveState() (json.RawMessage, error) {
+	return json.Marshal(t.state())
+}
+
+// loadState restores the tracker from the state store.
+func (t *changeTracker[T]) loadState(data json.RawMessage) error {
+	var state changeTrackerState[T]
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	t.restore(state)
+	return nil
+}
@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleDevicesCollector_Changes(t *testing.T) {
+	collector := &TailscaleDevicesCollector{
+		log:           slog.Default(),
+		aggregateOnly: true,
+	}
+
+	polls := [][]tailscale.Device{
+		{
+			{ID: "device-1", Name: "one", MachineKey: "mkey:1", NodeKey: "nodekey:1"},
+			{ID: "device-2", Name: "two", MachineKey: "mkey:2", NodeKey: "nodekey:2"},
+			{ID: "device-3", Name: "three", MachineKey: "mkey:3", NodeKey: "nodekey:3"},
+		},
+		{
+			{ID: "device-1", Name: "uno", MachineKey: "mkey:1", NodeKey: "nodekey:1b"},
+			{ID: "device-2", Name: "two", MachineKey: "mkey:2", NodeKey: "nodekey:2"},
+			{ID: "device-4", Name: "four", MachineKey: "mkey:4", NodeKey: "nodekey:4"},
+		},
+	}
+
+	var metrics []prometheus.Metric
+	for _, devices := range polls {
+		client := &MockTailscaleClient{
+			devicesClient: &MockDevicesClient{devices: devices},
+		}
+
+		ch := make(chan prometheus.Metric, 32)
+		if err := collector.Update(context.Background(), client, ch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(ch)
+
+		metrics = metrics[:0]
+		for metric := range ch {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(&TestMetricCollector{metrics: metrics})
+
+	expected := `
+# HELP tailscale_devices_added_total Number of devices added to the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_added_total counter
+tailscale_devices_added_total 1
+# HELP tailscale_devices_key_rotated_total Number of device machine or node key changes since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_key_rotated_total counter
+tailscale_devices_key_rotated_total 1
+# HELP tailscale_devices_removed_total Number of devices removed from the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_removed_total counter
+tailscale_devices_removed_total 1
+# HELP tailscale_devices_renamed_total Number of device renames since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_renamed_total counter
+tailscale_devices_renamed_total 1
+`
+	if err := testutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"tailscale_devices_added_total",
+		"tailscale_devices_key_rotated_total",
+		"tailscale_devices_removed_total",
+		"tailscale_devices_renamed_total",
+	); err != nil {
+		t.Errorf("metrics mismatch: %v", err)
+	}
+}
+
+func TestTailscaleUsersCollector_Changes(t *testing.T) {
+	collector := &TailscaleUsersCollector{
+		log:           slog.Default(),
+		aggregateOnly: true,
+	}
+
+	polls := [][]tailscale.User{
+		{
+			{ID: "user-1", LoginName: "alice", Role: "member", Status: "active"},
+			{ID: "user-2", LoginName: "bob", Role: "member", Status: "active"},
+			{ID: "user-3", LoginName: "carol", Role: "member", Status: "needs-approval"},
+		},
+		{
+			// Going idle is activity, not a lifecycle change
+			{ID: "user-1", LoginName: "alice", Role: "admin", Status: "idle"},
+			{ID: "user-2", LoginName: "bob", Role: "member", Status: "suspended"},
+			{ID: "user-3", LoginName: "carol", Role: "member", Status: "active"},
+		},
+		{
+			{ID: "user-1", LoginName: "alice", Role: "admin", Status: "active"},
+			{ID: "user-2", LoginName: "bob", Role: "member", Status: "suspended"},
+			{ID: "user-3", LoginName: "carol", Role: "member", Status: "idle"},
+		},
+	}
+
+	var metrics []prometheus.Metric
+	for _, users := range polls {
+		client := &MockTailscaleClient{
+			usersClient: &MockUsersClient{users: users},
+		}
+
+		ch := make(chan prometheus.Metric, 32)
+		if err := collector.Update(context.Background(), client, ch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(ch)
+
+		metrics = metrics[:0]
+		for metric := range ch {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(&TestMetricCollector{metrics: metrics})
+
+	expected := `
+# HELP tailscale_users_role_changed_total Number of user role changes since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_role_changed_total counter
+tailscale_users_role_changed_total 1
+# HELP tailscale_users_status_changed_total Number of user lifecycle status changes, excluding changes between active and idle, since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_status_changed_total counter
+tailscale_users_status_changed_total 2
+`
+	if err := testutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"tailscale_users_role_changed_total",
+		"tailscale_users_status_changed_total",
+	); err != nil {
+		t.Errorf("metrics mismatch: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+// collectorScopes lists the OAuth scopes each collector needs. They are not
+// requested, only reported when a collector is forbidden from its API.
+var collectorScopes = map[string][]string{
+	contactsSubsystem:        {"account_settings:read"},
+	devicesSubsystem:         {"devices:read", "devices:routes:read"},
+	dnsSubsystem:             {"dns:read"},
+	groupsCollector:          {"policy_file:read", "users:read"},
+	invitesSubsystem:         {"users:read", "devices:read", "device_invites:read"},
+	keysSubsystem:            {"auth_keys:read"},
+	logStreamingSubsystem:    {"log_streaming:read"},
+	policySubsystem:          {"policy_file:read"},
+	tagsSubsystem:            {"policy_file:read", "devices:read"},
+	tailnetSettingsSubsystem: {"feature_settings:read"},
+	usersSubsystem:           {"users:read"},
+	webhooksSubsystem:        {"webhooks:read"},
+}
+
+// Check statuses.
+const (
+	CheckOK        = "ok"
+	CheckForbidden = "forbidden"
+	CheckError     = "error"
+)
+
+// CheckResult is the outcome of checking a single collector.
+type CheckResult struct {
+	Collector string
+	Scopes    []string
+	Status    string
+	Err       error
+}
+
+// checkFunc makes the cheapest API calls covering the scopes of a collector.
+type checkFunc func(ctx context.Context, client TailscaleClient) error
+
+// collectorChecks lists the API calls checking each collector. They read a
+// single resource per scope rather than running a full Update, which may
+// make many calls, e.g. one per device or policy test.
+var collectorChecks = map[string]checkFunc{
+	contactsSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.Contacts().Get(ctx)
+		return err
+	},
+	devicesSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		devices, err := client.Devices().List(ctx)
+		if err != nil || len(devices) == 0 {
+			return err
+		}
+		_, err = client.Devices().SubnetRoutes(ctx, devices[0].ID)
+		return err
+	},
+	dnsSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.DNS().Preferences(ctx)
+		return err
+	},
+	groupsCollector: func(ctx context.Context, client TailscaleClient) error {
+		if _, err := client.PolicyFile().Raw(ctx); err != nil {
+			return err
+		}
+		_, err := client.Users().List(ctx, nil, nil)
+		return err
+	},
+	invitesSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		if _, err := client.Invites().UserInvites(ctx); err != nil {
+			return err
+		}
+		devices, err := client.Devices().List(ctx)
+		if err != nil || len(devices) == 0 {
+			return err
+		}
+		_, err = client.Invites().DeviceInvites(ctx, devices[0].ID)
+		return err
+	},
+	keysSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.Keys().List(ctx, false)
+		return err
+	},
+	logStreamingSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.Logging().LogstreamConfiguration(ctx, tailscale.LogTypeConfig)
+		// The API responds with 404 when no destination is configured.
+		if tailscale.IsNotFound(err) {
+			return nil
+		}
+		return err
+	},
+	policySubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.PolicyFile().Raw(ctx)
+		return err
+	},
+	tagsSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		if _, err := client.PolicyFile().Raw(ctx); err != nil {
+			return err
+		}
+		_, err := client.Devices().List(ctx)
+		return err
+	},
+	tailnetSettingsSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.TailnetSettings().Get(ctx)
+		return err
+	},
+	usersSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.Users().List(ctx, nil, nil)
+		return err
+	},
+	webhooksSubsystem: func(ctx context.Context, client TailscaleClient) error {
+		_, err := client.Webhooks().List(ctx)
+		return err
+	},
+}
+
+// Check makes the API calls of every collector once and reports whether the
+// APIs it uses are accessible with the configured credentials.
+func (t *TailscaleCollector) Check(ctx context.Context) []CheckResult {
+	results := make([]CheckResult, 0, len(t.Collectors))
+	for _, name := range slices.Sorted(maps.Keys(t.Collectors)) {
+		err := collectorChecks[name](ctx, t.client)
+
+		result := CheckResult{
+			Collector: name,
+			Scopes:    collectorScopes[name],
+			Status:    CheckOK,
+			Err:       err,
+		}
+		if err != nil {
+			result.Status = CheckError
+			if isForbidden(err) {
+				result.Status = CheckForbidden
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleCollector_Check(t *testing.T) {
+	collector := &TailscaleCollector{
+		logger: slog.Default(),
+		client: &MockTailscaleClient{
+			// Devices can be listed, but not their routes
+			devicesClient: &MockDevicesClient{
+				devices:   []tailscale.Device{{ID: "device-123"}},
+				routesErr: apiError{Message: "forbidden", status: http.StatusForbidden},
+			},
+			keysClient: &MockKeysClient{
+				keysErr: apiError{Message: "forbidden", status: http.StatusForbidden},
+			},
+			usersClient: &MockUsersClient{
+				usersErr: errors.New("connection refused"),
+			},
+			webhooksClient: &MockWebhooksClient{
+				webhooks: []tailscale.Webhook{},
+			},
+			// The check reads the policy file instead of running its tests
+			policyFileClient: &MockPolicyFileClient{
+				raw: &tailscale.RawACL{HuJSON: `{"tests": [{"src": "a@example.com"}]}`},
+				validateErr: func(any) error {
+					t.Error("expected the check not to validate the policy file")
+					return nil
+				},
+			},
+		},
+		Collectors: map[string]Collector{
+			devicesSubsystem:  &TailscaleDevicesCollector{log: slog.Default()},
+			policySubsystem:   &TailscalePolicyCollector{log: slog.Default()},
+			keysSubsystem:     &TailscaleKeysCollector{log: slog.Default()},
+			usersSubsystem:    &TailscaleUsersCollector{log: slog.Default()},
+			webhooksSubsystem: &TailscaleWebhooksCollector{log: slog.Default()},
+		},
+	}
+
+	expected := map[string]string{
+		devicesSubsystem:  CheckForbidden,
+		keysSubsystem:     CheckForbidden,
+		policySubsystem:   CheckOK,
+		usersSubsystem:    CheckError,
+		webhooksSubsystem: CheckOK,
+	}
+
+	results := collector.Check(context.Background())
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for _, result := range results {
+		if result.Status != expected[result.Collector] {
+			t.Errorf("expected %s to be %s, got %s (%v)",
+				result.Collector, expected[result.Collector], result.Status, result.Err)
+		}
+		if len(result.Scopes) == 0 {
+			t.Errorf("expected scopes for %s", result.Collector)
+		}
+	}
+}
+
+func TestCollectorChecks(t *testing.T) {
+	for _, name := range CollectorNames() {
+		if collectorChecks[name] == nil {
+			t.Errorf("expected a check for collector %s", name)
+		}
+		if len(collectorScopes[name]) == 0 {
+			t.Errorf("expected scopes for collector %s", name)
+		}
+	}
+}
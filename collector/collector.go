@@ -2,8 +2,12 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -14,14 +18,15 @@ import (
 
 const (
 	namespace = "tailscale"
+
+	// tailnetSubsystem holds tailnet-level aggregates of per-entity metrics.
+	tailnetSubsystem = "tailnet"
 )
 
 var (
 	factories = make(
 		map[string]func(collectorConfig) (Collector, error),
 	)
-	initiatedCollectorsMtx = sync.Mutex{}
-	initiatedCollectors    = make(map[string]Collector)
 )
 
 var (
@@ -43,6 +48,12 @@ var (
 		"tailscale_exporter: Whether a collector succeeded.",
 		[]string{"collector"},
 	)
+	collectorEnabledDesc = newDesc(
+		"exporter",
+		"collector_enabled",
+		"tailscale_exporter: Whether a collector is enabled, or the reason it was disabled.",
+		[]string{"collector", "reason"},
+	)
 )
 
 func boolAsFloat(b bool) float64 {
@@ -54,6 +65,100 @@ func boolAsFloat(b bool) float64 {
 
 type collectorConfig struct {
 	logger *slog.Logger
+
+	policyTestsFile       string
+	policyTestsInterval   time.Duration
+	deviceInvitesInterval time.Duration
+	aggregateOnly         bool
+	statePath             string
+	collectors            []string
+	forbiddenBackoff      time.Duration
+	logLevels             map[string]slog.Level
+	expiryWindows         []time.Duration
+	previous              *TailscaleCollector
+}
+
+// Option configures optional collector behaviour.
+type Option func(*collectorConfig)
+
+// WithPolicyTests configures the policy collector to run the tests in file
+// instead of the tests embedded in the policy file, at most once per interval.
+func WithPolicyTests(file string, interval time.Duration) Option {
+	return func(c *collectorConfig) {
+		c.policyTestsFile = file
+		c.policyTestsInterval = interval
+	}
+}
+
+// WithDeviceInvitesInterval configures the invites collector to list the share
+// invites of every device at most once per interval.
+func WithDeviceInvitesInterval(interval time.Duration) Option {
+	return func(c *collectorConfig) {
+		c.deviceInvitesInterval = interval
+	}
+}
+
+// WithAggregateOnly suppresses per-device and per-user series, leaving only the
+// tailnet-level aggregates.
+func WithAggregateOnly(aggregateOnly bool) Option {
+	return func(c *collectorConfig) {
+		c.aggregateOnly = aggregateOnly
+	}
+}
+
+// WithStatePath persists the inventory of devices, users, keys and tailnet
+// settings to path after every collection and restores it on startup, so
+// change counters survive restarts.
+func WithStatePath(path string) Option {
+	return func(c *collectorConfig) {
+		c.statePath = path
+	}
+}
+
+// WithCollectors restricts the collectors that run to names. All registered
+// collectors run if names is empty.
+func WithCollectors(names ...string) Option {
+	return func(c *collectorConfig) {
+		c.collectors = names
+	}
+}
+
+// WithForbiddenBackoff sets how long a collector stays disabled after the API
+// rejected the credentials with 401 or 403, before it is tried again.
+func WithForbiddenBackoff(backoff time.Duration) Option {
+	return func(c *collectorConfig) {
+		c.forbiddenBackoff = backoff
+	}
+}
+
+// WithLogLevels overrides the log level of individual collectors, by name.
+// It only applies to loggers with a handler from NewLogHandler.
+func WithLogLevels(levels map[string]slog.Level) Option {
+	return func(c *collectorConfig) {
+		c.logLevels = levels
+	}
+}
+
+// WithExpiryWindows sets the windows devices and keys expiring soon are
+// counted in, 7 and 30 days by default.
+func WithExpiryWindows(windows ...time.Duration) Option {
+	return func(c *collectorConfig) {
+		c.expiryWindows = windows
+	}
+}
+
+// WithPrevious carries the inventory, change counters and collector statuses
+// of prev over to the new collector, e.g. when the configuration is reloaded.
+// It takes precedence over the state restored from WithStatePath.
+func WithPrevious(prev *TailscaleCollector) Option {
+	return func(c *collectorConfig) {
+		c.previous = prev
+	}
+}
+
+// CollectorNames returns the names of all registered collectors.
+func CollectorNames() []string {
+	return slices.Sorted(maps.Keys(factories))
 }
 
 func newDesc(
@@ -88,6 +193,12 @@ type TailscaleCollector struct {
 
 	Collectors map[string]Collector
 	logger     *slog.Logger
+	loggers    map[string]*slog.Logger
+	state      *stateStore
+	forbidden  *forbiddenCollectors
+	statuses   collectorStatuses
+
+	scrapeErrors *prometheus.CounterVec
 }
 
 type TailscaleClient interface {
@@ -96,6 +207,11 @@ type TailscaleClient interface {
 	Devices() DevicesAPI
 	Users() UsersAPI
 	TailnetSettings() TailnetSettingsAPI
+	PolicyFile() PolicyFileAPI
+	Webhooks() WebhooksAPI
+	Contacts() ContactsAPI
+	Logging() LoggingAPI
+	Invites() InvitesAPI
 }
 
 // KeysAPI is the subset of *tailscale.KeysResource you actually use
@@ -107,6 +223,18 @@ type KeysAPI interface {
 type DNSAPI interface {
 	Nameservers(ctx context.Context) ([]string, error)
 	Preferences(ctx context.Context) (*tailscale.DNSPreferences, error)
+	SplitDNS(ctx context.Context) (tailscale.SplitDNSResponse, error)
+	SearchPaths(ctx context.Context) ([]string, error)
+	Configuration(ctx context.Context) (*DNSConfiguration, error)
+}
+
+// DNSConfiguration is the subset of the tailnet DNS configuration that is not
+// available through the other DNS endpoints.
+type DNSConfiguration struct {
+	Preferences struct {
+		OverrideLocalDNS bool `json:"overrideLocalDNS"`
+		MagicDNS         bool `json:"magicDNS"`
+	} `json:"preferences"`
 }
 
 // DevicesAPI is the subset of *tailscale.DevicesResource you actually use
@@ -129,23 +257,87 @@ type TailnetSettingsAPI interface {
 	Get(ctx context.Context) (*tailscale.TailnetSettings, error)
 }
 
+// PolicyFileAPI is the subset of *tailscale.PolicyFileResource you actually use
+type PolicyFileAPI interface {
+	Get(ctx context.Context) (*tailscale.ACL, error)
+	Raw(ctx context.Context) (*tailscale.RawACL, error)
+	Validate(ctx context.Context, acl any) error
+}
+
+// WebhooksAPI is the subset of *tailscale.WebhooksResource you actually use
+type WebhooksAPI interface {
+	List(ctx context.Context) ([]tailscale.Webhook, error)
+}
+
+// ContactsAPI is the subset of *tailscale.ContactsResource you actually use
+type ContactsAPI interface {
+	Get(ctx context.Context) (*tailscale.Contacts, error)
+}
+
+// LoggingAPI is the subset of *tailscale.LoggingResource you actually use
+type LoggingAPI interface {
+	LogstreamConfiguration(
+		ctx context.Context,
+		logType tailscale.LogType,
+	) (*tailscale.LogstreamConfiguration, error)
+}
+
+// InvitesAPI lists user and device invites, which the tailscale client
+// library does not cover yet.
+type InvitesAPI interface {
+	UserInvites(ctx context.Context) ([]UserInvite, error)
+	DeviceInvites(ctx context.Context, deviceID string) ([]DeviceInvite, error)
+}
+
 // TailscaleClientWrapper wraps the real tailscale.Client to implement our TailscaleClient interface
 type TailscaleClientWrapper struct {
 	client *tailscale.Client
+	api    *apiClient
 }
 
 func NewTailscaleClientWrapper(client *tailscale.Client) *TailscaleClientWrapper {
+	api := &apiClient{
+		http:    client.HTTP,
+		baseURL: client.BaseURL,
+		tailnet: client.Tailnet,
+	}
+	if api.http == nil {
+		api.http = http.DefaultClient
+	}
+	if api.baseURL == nil {
+		api.baseURL = defaultAPIBaseURL
+	}
+	if api.tailnet == "" {
+		api.tailnet = "-"
+	}
+
 	return &TailscaleClientWrapper{
 		client: client,
+		api:    api,
 	}
 }
 
+// dnsResource extends *tailscale.DNSResource with the DNS configuration
+// endpoint.
+type dnsResource struct {
+	*tailscale.DNSResource
+	api *apiClient
+}
+
+func (r *dnsResource) Configuration(ctx context.Context) (*DNSConfiguration, error) {
+	var configuration DNSConfiguration
+	if err := r.api.getTailnet(ctx, &configuration, "dns", "configuration"); err != nil {
+		return nil, err
+	}
+	return &configuration, nil
+}
+
 func (w *TailscaleClientWrapper) Keys() KeysAPI {
 	return w.client.Keys()
 }
 
 func (w *TailscaleClientWrapper) DNS() DNSAPI {
-	return w.client.DNS()
+	return &dnsResource{DNSResource: w.client.DNS(), api: w.api}
 }
 
 func (w *TailscaleClientWrapper) Devices() DevicesAPI {
@@ -160,36 +352,119 @@ func (w *TailscaleClientWrapper) TailnetSettings() TailnetSettingsAPI {
 	return w.client.TailnetSettings()
 }
 
+func (w *TailscaleClientWrapper) PolicyFile() PolicyFileAPI {
+	return &policyFileResource{PolicyFileResource: w.client.PolicyFile(), api: w.api}
+}
+
+func (w *TailscaleClientWrapper) Webhooks() WebhooksAPI {
+	return w.client.Webhooks()
+}
+
+func (w *TailscaleClientWrapper) Contacts() ContactsAPI {
+	return w.client.Contacts()
+}
+
+func (w *TailscaleClientWrapper) Logging() LoggingAPI {
+	return w.client.Logging()
+}
+
+func (w *TailscaleClientWrapper) Invites() InvitesAPI {
+	return &invitesResource{api: w.api}
+}
+
 // NewTailscaleCollector creates the Tailscale collector.
 func NewTailscaleCollector(
 	logger *slog.Logger,
 	httpClient *http.Client,
 	tailnet string,
+	opts ...Option,
 ) (*TailscaleCollector, error) {
 	t := &TailscaleCollector{
 		logger: logger,
+		scrapeErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "scrape",
+				Name:      "collector_errors_total",
+				Help:      "tailscale_exporter: Number of collector errors, by kind.",
+			},
+			[]string{"collector", "kind"},
+		),
+	}
+
+	config := collectorConfig{
+		policyTestsInterval:   DefaultPolicyTestsInterval,
+		deviceInvitesInterval: DefaultDeviceInvitesInterval,
+		forbiddenBackoff:      DefaultForbiddenBackoff,
+		expiryWindows:         DefaultExpiryWindows(),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	t.forbidden = &forbiddenCollectors{backoff: config.forbiddenBackoff}
+
+	windows := make(map[time.Duration]bool)
+	for _, window := range config.expiryWindows {
+		if window <= 0 || windows[window] {
+			return nil, fmt.Errorf(
+				"invalid expiry window %s, windows must be positive and unique",
+				window,
+			)
+		}
+		windows[window] = true
+	}
+
+	var state map[string]json.RawMessage
+	if config.statePath != "" {
+		t.state = &stateStore{path: config.statePath, tailnet: tailnet}
+		var err error
+		state, err = t.state.load()
+		if err != nil {
+			logger.Warn("Ignoring exporter state", "path", config.statePath, "error", err.Error())
+		}
+	}
+
+	enabled := config.collectors
+	if len(enabled) == 0 {
+		enabled = CollectorNames()
+	}
+	for _, key := range enabled {
+		if _, ok := factories[key]; !ok {
+			return nil, fmt.Errorf("unknown collector %q", key)
+		}
 	}
 
 	collectors := make(map[string]Collector)
-	initiatedCollectorsMtx.Lock()
-	defer initiatedCollectorsMtx.Unlock()
-	for key := range factories {
-		if collector, ok := initiatedCollectors[key]; ok {
-			collectors[key] = collector
-		} else {
-			coll, err := factories[key](collectorConfig{
-				logger: logger.With("collector", key),
-			})
-			if err != nil {
-				return nil, err
+	t.loggers = make(map[string]*slog.Logger)
+	for _, key := range enabled {
+		collLogger := logger
+		if level, ok := config.logLevels[key]; ok {
+			collLogger = slog.New(withLogLevel(logger.Handler(), level))
+		}
+		t.loggers[key] = collLogger.With("collector", key)
+
+		collConfig := config
+		collConfig.logger = t.loggers[key]
+		coll, err := factories[key](collConfig)
+		if err != nil {
+			return nil, err
+		}
+		if sc, ok := coll.(statefulCollector); ok && state[key] != nil {
+			if err := sc.loadState(state[key]); err != nil {
+				logger.Warn("Ignoring collector state", "collector", key, "error", err.Error())
 			}
-			collectors[key] = coll
-			initiatedCollectors[key] = coll
 		}
+		collectors[key] = coll
 	}
 
 	t.Collectors = collectors
+	if config.previous != nil {
+		t.inherit(config.previous)
+	}
 
+	if httpClient != nil {
+		httpClient = withAPIErrors(httpClient)
+	}
 	client := &tailscale.Client{
 		HTTP:    httpClient,
 		Tailnet: tailnet,
@@ -204,23 +479,82 @@ func (t *TailscaleCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- upDesc
 	ch <- scrapeDurationDesc
 	ch <- scrapeSuccessDesc
+	ch <- collectorEnabledDesc
+	t.scrapeErrors.Describe(ch)
 }
 
 func (t *TailscaleCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.TODO()
+	t.collect(context.TODO(), ch)
+}
+
+func (t *TailscaleCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) {
+	ctx = withScrapeID(ctx)
 	wg := sync.WaitGroup{}
 	wg.Add(len(t.Collectors))
 
 	for name, c := range t.Collectors {
 		go func(name string, c Collector) {
-			execute(ctx, name, c, t.client, ch, t.logger)
-			wg.Done()
+			defer wg.Done()
+
+			if t.forbidden.skip(name, time.Now()) {
+				ch <- prometheus.MustNewConstMetric(
+					collectorEnabledDesc, prometheus.GaugeValue, 0,
+					name, collectorDisabledForbidden,
+				)
+				return
+			}
+
+			logger := t.collectorLogger(name)
+			err := execute(ctx, name, c, t.client, ch, logger)
+			if err != nil {
+				t.scrapeErrors.WithLabelValues(name, errorKind(err)).Inc()
+			}
+			forbidden := t.forbidden.observe(ctx, logger, name, err, time.Now())
+			t.statuses.record(name, err, forbidden, time.Now())
+			if forbidden {
+				ch <- prometheus.MustNewConstMetric(
+					collectorEnabledDesc, prometheus.GaugeValue, 0,
+					name, collectorDisabledForbidden,
+				)
+				return
+			}
+			ch <- prometheus.MustNewConstMetric(collectorEnabledDesc, prometheus.GaugeValue, 1, name, "")
 		}(name, c)
 	}
 	wg.Wait()
+	t.scrapeErrors.Collect(ch)
+	if t.state != nil {
+		if err := t.state.save(t.Collectors); err != nil {
+			t.logger.ErrorContext(
+				ctx,
+				"Error saving exporter state",
+				"path",
+				t.state.path,
+				"error",
+				err.Error(),
+			)
+		}
+	}
 	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
 }
 
+// collectorLogger returns the logger of the named collector.
+func (t *TailscaleCollector) collectorLogger(name string) *slog.Logger {
+	if logger, ok := t.loggers[name]; ok {
+		return logger
+	}
+	return t.logger.With("collector", name)
+}
+
+// PolicyGroups returns the members of the groups defined in the policy file.
+func (t *TailscaleCollector) PolicyGroups(ctx context.Context) (map[string][]string, error) {
+	acl, err := t.client.PolicyFile().Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return acl.Groups, nil
+}
+
 func execute(
 	ctx context.Context,
 	name string,
@@ -228,7 +562,7 @@ func execute(
 	client TailscaleClient,
 	ch chan<- prometheus.Metric,
 	logger *slog.Logger,
-) {
+) error {
 	begin := time.Now()
 	err := c.Update(ctx, client, ch)
 	duration := time.Since(begin)
@@ -238,8 +572,6 @@ func execute(
 		logger.ErrorContext(
 			ctx,
 			"collector failed",
-			"name",
-			name,
 			"duration_seconds",
 			duration.Seconds(),
 			"err",
@@ -247,9 +579,10 @@ func execute(
 		)
 		success = 0
 	} else {
-		logger.DebugContext(ctx, "collector succeeded", "name", name, "duration_seconds", duration.Seconds())
+		logger.DebugContext(ctx, "collector succeeded", "duration_seconds", duration.Seconds())
 		success = 1
 	}
 	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
 	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
+	return err
 }
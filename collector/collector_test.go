@@ -41,6 +41,12 @@ type MockDNSClient struct {
 	nameserversErr error
 	preferences    *tailscale.DNSPreferences
 	preferencesErr error
+	splitDNS       tailscale.SplitDNSResponse
+	splitDNSErr    error
+	searchPaths    []string
+	searchPathsErr error
+	configuration  *DNSConfiguration
+	configErr      error
 }
 
 func (m *MockDNSClient) Nameservers(ctx context.Context) ([]string, error) {
@@ -57,6 +63,30 @@ func (m *MockDNSClient) Preferences(ctx context.Context) (*tailscale.DNSPreferen
 	return m.preferences, nil
 }
 
+func (m *MockDNSClient) SplitDNS(ctx context.Context) (tailscale.SplitDNSResponse, error) {
+	if m.splitDNSErr != nil {
+		return nil, m.splitDNSErr
+	}
+	return m.splitDNS, nil
+}
+
+func (m *MockDNSClient) SearchPaths(ctx context.Context) ([]string, error) {
+	if m.searchPathsErr != nil {
+		return nil, m.searchPathsErr
+	}
+	return m.searchPaths, nil
+}
+
+func (m *MockDNSClient) Configuration(ctx context.Context) (*DNSConfiguration, error) {
+	if m.configErr != nil {
+		return nil, m.configErr
+	}
+	if m.configuration == nil {
+		return &DNSConfiguration{}, nil
+	}
+	return m.configuration, nil
+}
+
 // MockDevicesClient implements the DevicesAPI interface for testing
 type MockDevicesClient struct {
 	devices    []tailscale.Device
@@ -117,6 +147,109 @@ func (m *MockTailnetSettingsClient) Get(ctx context.Context) (*tailscale.Tailnet
 	return m.settings, nil
 }
 
+// MockPolicyFileClient implements the PolicyFileAPI interface for testing
+type MockPolicyFileClient struct {
+	acl         *tailscale.ACL
+	aclErr      error
+	raw         *tailscale.RawACL
+	rawErr      error
+	validateErr func(acl any) error
+}
+
+func (m *MockPolicyFileClient) Get(ctx context.Context) (*tailscale.ACL, error) {
+	if m.aclErr != nil {
+		return nil, m.aclErr
+	}
+	return m.acl, nil
+}
+
+func (m *MockPolicyFileClient) Raw(ctx context.Context) (*tailscale.RawACL, error) {
+	if m.rawErr != nil {
+		return nil, m.rawErr
+	}
+	return m.raw, nil
+}
+
+func (m *MockPolicyFileClient) Validate(ctx context.Context, acl any) error {
+	if m.validateErr != nil {
+		return m.validateErr(acl)
+	}
+	return nil
+}
+
+// MockWebhooksClient implements the WebhooksAPI interface for testing
+type MockWebhooksClient struct {
+	webhooks    []tailscale.Webhook
+	webhooksErr error
+}
+
+func (m *MockWebhooksClient) List(ctx context.Context) ([]tailscale.Webhook, error) {
+	if m.webhooksErr != nil {
+		return nil, m.webhooksErr
+	}
+	return m.webhooks, nil
+}
+
+// MockContactsClient implements the ContactsAPI interface for testing
+type MockContactsClient struct {
+	contacts    *tailscale.Contacts
+	contactsErr error
+}
+
+func (m *MockContactsClient) Get(ctx context.Context) (*tailscale.Contacts, error) {
+	if m.contactsErr != nil {
+		return nil, m.contactsErr
+	}
+	return m.contacts, nil
+}
+
+// MockLoggingClient implements the LoggingAPI interface for testing
+type MockLoggingClient struct {
+	configurations map[tailscale.LogType]*tailscale.LogstreamConfiguration
+	errs           map[tailscale.LogType]error
+}
+
+func (m *MockLoggingClient) LogstreamConfiguration(
+	ctx context.Context,
+	logType tailscale.LogType,
+) (*tailscale.LogstreamConfiguration, error) {
+	if err, ok := m.errs[logType]; ok {
+		return nil, err
+	}
+	if configuration, ok := m.configurations[logType]; ok {
+		return configuration, nil
+	}
+	return &tailscale.LogstreamConfiguration{}, nil
+}
+
+// MockInvitesClient implements the InvitesAPI interface for testing
+type MockInvitesClient struct {
+	userInvites    []UserInvite
+	userInvitesErr error
+	deviceInvites  map[string][]DeviceInvite
+	// deviceInvitesErr holds errors to return per device ID
+	deviceInvitesErr   map[string]error
+	deviceInvitesCalls int
+}
+
+func (m *MockInvitesClient) UserInvites(ctx context.Context) ([]UserInvite, error) {
+	if m.userInvitesErr != nil {
+		return nil, m.userInvitesErr
+	}
+	return m.userInvites, nil
+}
+
+func (m *MockInvitesClient) DeviceInvites(
+	ctx context.Context,
+	deviceID string,
+) ([]DeviceInvite, error) {
+	m.deviceInvitesCalls++
+	if err := m.deviceInvitesErr[deviceID]; err != nil {
+		return nil, err
+	}
+	return m.deviceInvites[deviceID], nil
+}
+
 // MockTailscaleClient implements the TailscaleClient interface for testing
 type MockTailscaleClient struct {
 	dnsClient             *MockDNSClient
@@ -124,6 +257,11 @@ type MockTailscaleClient struct {
 	devicesClient         *MockDevicesClient
 	usersClient           *MockUsersClient
 	tailnetSettingsClient *MockTailnetSettingsClient
+	policyFileClient      *MockPolicyFileClient
+	webhooksClient        *MockWebhooksClient
+	contactsClient        *MockContactsClient
+	loggingClient         *MockLoggingClient
+	invitesClient         *MockInvitesClient
 }
 
 func (m *MockTailscaleClient) DNS() DNSAPI {
@@ -145,3 +283,23 @@ func (m *MockTailscaleClient) Users() UsersAPI {
 func (m *MockTailscaleClient) TailnetSettings() TailnetSettingsAPI {
 	return m.tailnetSettingsClient
 }
+
+func (m *MockTailscaleClient) PolicyFile() PolicyFileAPI {
+	return m.policyFileClient
+}
+
+func (m *MockTailscaleClient) Webhooks() WebhooksAPI {
+	return m.webhooksClient
+}
+
+func (m *MockTailscaleClient) Contacts() ContactsAPI {
+	return m.contactsClient
+}
+
+func (m *MockTailscaleClient) Logging() LoggingAPI {
+	return m.loggingClient
+}
+
+func (m *MockTailscaleClient) Invites() InvitesAPI {
+	return m.invitesClient
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+const contactsSubsystem = "contacts"
+
+var (
+	contactsInfoDesc = newDesc(
+		contactsSubsystem,
+		"info",
+		"Tailnet contact information.",
+		[]string{"type", "email", "fallback_email"},
+	)
+	contactsConfiguredDesc = newDesc(
+		contactsSubsystem,
+		"configured",
+		"Whether an email address is set for the tailnet contact.",
+		[]string{"type"},
+	)
+	contactsVerifiedDesc = newDesc(
+		contactsSubsystem,
+		"verified",
+		"Whether the tailnet contact email address is verified.",
+		[]string{"type"},
+	)
+)
+
+type TailscaleContactsCollector struct {
+	log *slog.Logger
+}
+
+func init() {
+	registerCollector(contactsSubsystem, NewTailscaleContactsCollector)
+}
+
+func NewTailscaleContactsCollector(config collectorConfig) (Collector, error) {
+	return &TailscaleContactsCollector{
+		log: config.logger,
+	}, nil
+}
+
+func (c TailscaleContactsCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting contacts metrics")
+
+	contacts, err := client.Contacts().Get(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale contacts",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	for contactType, contact := range map[tailscale.ContactType]tailscale.Contact{
+		tailscale.ContactAccount:  contacts.Account,
+		tailscale.ContactSupport:  contacts.Support,
+		tailscale.ContactSecurity: contacts.Security,
+	} {
+		ch <- prometheus.MustNewConstMetric(
+			contactsInfoDesc, prometheus.GaugeValue, 1,
+			string(contactType), contact.Email, contact.FallbackEmail,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			contactsConfiguredDesc, prometheus.GaugeValue, boolAsFloat(contact.Email != ""),
+			string(contactType),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			contactsVerifiedDesc, prometheus.GaugeValue,
+			boolAsFloat(contact.Email != "" && !contact.NeedsVerification),
+			string(contactType),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleContactsCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		expectedMetrics string
+		expectError     bool
+	}{
+		{
+			name: "successful collection with contacts",
+			mockClient: &MockTailscaleClient{
+				contactsClient: &MockContactsClient{
+					contacts: &tailscale.Contacts{
+						Account: tailscale.Contact{Email: "owner@example.com"},
+						Support: tailscale.Contact{
+							Email:             "support@example.com",
+							FallbackEmail:     "owner@example.com",
+							NeedsVerification: true,
+						},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_contacts_configured Whether an email address is set for the tailnet contact.
+# TYPE tailscale_contacts_configured gauge
+tailscale_contacts_configured{type="account"} 1
+tailscale_contacts_configured{type="security"} 0
+tailscale_contacts_configured{type="support"} 1
+# HELP tailscale_contacts_info Tailnet contact information.
+# TYPE tailscale_contacts_info gauge
+tailscale_contacts_info{email="",fallback_email="",type="security"} 1
+tailscale_contacts_info{email="owner@example.com",fallback_email="",type="account"} 1
+tailscale_contacts_info{email="support@example.com",fallback_email="owner@example.com",type="support"} 1
+# HELP tailscale_contacts_verified Whether the tailnet contact email address is verified.
+# TYPE tailscale_contacts_verified gauge
+tailscale_contacts_verified{type="account"} 1
+tailscale_contacts_verified{type="security"} 0
+tailscale_contacts_verified{type="support"} 0
+`,
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscaleContactsCollector{
+				log: logger,
+			}
+
+			ch := make(chan prometheus.Metric, 32)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// Compare the metrics
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
 )
 
 const devicesSubsystem = "devices"
@@ -46,6 +50,15 @@ var (
 			"name", "hostname", "os", "user",
 		},
 	)
+	devicesExpiresInDesc = newDesc(
+		devicesSubsystem,
+		"expires_in_seconds",
+		"Seconds until the device key expires, negative once expired. Not exported for devices with key expiry disabled",
+		[]string{
+			"id",
+			"name", "hostname", "os", "user",
+		},
+	)
 	devicesCreatedDesc = newDesc(
 		devicesSubsystem,
 		"created_timestamp",
@@ -118,10 +131,101 @@ var (
 		"Whether device blocks incoming connections",
 		[]string{"id", "name", "hostname", "os", "user"},
 	)
+	devicesTailnetLockSignedDesc = newDesc(
+		devicesSubsystem,
+		"tailnet_lock_signed",
+		"Whether the node key of device is signed by Tailnet Lock, i.e. it has no Tailnet Lock error. Always 1 with Tailnet Lock disabled",
+		[]string{"id", "name", "hostname", "os", "user"},
+	)
+	devicesTailnetLockErrorDesc = newDesc(
+		devicesSubsystem,
+		"tailnet_lock_error",
+		"Tailnet Lock error reported for device",
+		[]string{"id", "name", "hostname", "os", "user", "error"},
+	)
+	devicesAddedDesc = newDesc(
+		devicesSubsystem,
+		"added_total",
+		"Number of devices added to the tailnet since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	devicesRemovedDesc = newDesc(
+		devicesSubsystem,
+		"removed_total",
+		"Number of devices removed from the tailnet since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	devicesRenamedDesc = newDesc(
+		devicesSubsystem,
+		"renamed_total",
+		"Number of device renames since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	devicesKeyRotatedDesc = newDesc(
+		devicesSubsystem,
+		"key_rotated_total",
+		"Number of device machine or node key changes since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	tailnetDevicesDesc = newDesc(
+		tailnetSubsystem,
+		"devices",
+		"Number of devices in the tailnet",
+		[]string{"os", "online", "authorized", "external", "update_available", "ephemeral"},
+	)
+	tailnetDevicesTailnetLockUnsignedDesc = newDesc(
+		tailnetSubsystem,
+		"devices_tailnet_lock_unsigned",
+		"Number of devices locked out because their node key is not signed by Tailnet Lock, i.e. with a Tailnet Lock error",
+		[]string{},
+	)
+	tailnetDevicesExpiringDesc = newDesc(
+		tailnetSubsystem,
+		"devices_expiring",
+		"Number of devices whose key expires within the window, excluding devices with key expiry disabled",
+		[]string{"window"},
+	)
+)
+
+// devicesAggregateKey holds the label values devices are aggregated by.
+type devicesAggregateKey struct {
+	os              string
+	online          bool
+	authorized      bool
+	external        bool
+	updateAvailable bool
+	ephemeral       bool
+}
+
+const (
+	deviceChangeRenamed    = "renamed"
+	deviceChangeKeyRotated = "key_rotated"
 )
 
+// devicesChangeDescs maps the kinds of device changes to their counters.
+var devicesChangeDescs = map[string]*prometheus.Desc{
+	changeAdded:            devicesAddedDesc,
+	changeRemoved:          devicesRemovedDesc,
+	deviceChangeRenamed:    devicesRenamedDesc,
+	deviceChangeKeyRotated: devicesKeyRotatedDesc,
+}
+
+// deviceState is the part of a device that change detection compares.
+type deviceState struct {
+	Name       string `json:"name"`
+	Hostname   string `json:"hostname"`
+	User       string `json:"user"`
+	MachineKey string `json:"machine_key"`
+	NodeKey    string `json:"node_key"`
+}
+
 type TailscaleDevicesCollector struct {
-	log *slog.Logger
+	log           *slog.Logger
+	aggregateOnly bool
+	expiryWindows []time.Duration
+
+	changes  changeTracker[deviceState]
+	snapshot snapshot[[]tailscale.Device]
 }
 
 func init() {
@@ -130,11 +234,13 @@ func init() {
 
 func NewTailscaleDevicesCollector(config collectorConfig) (Collector, error) {
 	return &TailscaleDevicesCollector{
-		log: config.logger,
+		log:           config.logger,
+		aggregateOnly: config.aggregateOnly,
+		expiryWindows: config.expiryWindows,
 	}, nil
 }
 
-func (c TailscaleDevicesCollector) Update(
+func (c *TailscaleDevicesCollector) Update(
 	ctx context.Context,
 	client TailscaleClient,
 	ch chan<- prometheus.Metric,
@@ -151,9 +257,43 @@ func (c TailscaleDevicesCollector) Update(
 		)
 		return err
 	}
+	c.snapshot.set(devices)
+
+	now := timeNow()
+	unsigned := 0
+	expiring := newExpiringCounts(c.expiryWindows)
+	aggregates := make(map[devicesAggregateKey]int)
+	inventory := make(map[string]deviceState, len(devices))
 
 	// Device metrics
 	for _, device := range devices {
+		inventory[device.ID] = deviceState{
+			Name:       device.Name,
+			Hostname:   device.Hostname,
+			User:       device.User,
+			MachineKey: device.MachineKey,
+			NodeKey:    device.NodeKey,
+		}
+		if device.TailnetLockError != "" {
+			unsigned++
+		}
+		expires := !device.KeyExpiryDisabled && !device.Expires.IsZero()
+		if expires {
+			expiring.add(device.Expires.Sub(now))
+		}
+		aggregates[devicesAggregateKey{
+			os:              device.OS,
+			online:          isOnline(device.LastSeen.Time),
+			authorized:      device.Authorized,
+			external:        device.IsExternal,
+			updateAvailable: device.UpdateAvailable,
+			ephemeral:       device.IsEphemeral,
+		}]++
+
+		if c.aggregateOnly {
+			continue
+		}
+
 		tailscaleIP := ""
 		if len(device.Addresses) > 0 {
 			tailscaleIP = device.Addresses[0]
@@ -165,11 +305,7 @@ func (c TailscaleDevicesCollector) Update(
 			device.User, tailscaleIP, device.MachineKey, device.NodeKey)
 
 		// Device status metrics
-		online := 0.0
-		if time.Since(device.LastSeen.Time) < 5*time.Minute {
-			online = 1.0
-		}
-		ch <- prometheus.MustNewConstMetric(devicesOnlineDesc, prometheus.GaugeValue, online,
+		ch <- prometheus.MustNewConstMetric(devicesOnlineDesc, prometheus.GaugeValue, boolAsFloat(isOnline(device.LastSeen.Time)),
 			device.ID, device.Name, device.Hostname, device.OS, device.User)
 
 		authorized := 0.0
@@ -207,6 +343,17 @@ func (c TailscaleDevicesCollector) Update(
 		ch <- prometheus.MustNewConstMetric(devicesBlocksIncomingDesc, prometheus.GaugeValue, blocksIncoming,
 			device.ID, device.Name, device.Hostname, device.OS, device.User)
 
+		// Tailnet Lock metrics
+		// The API returns a Tailnet Lock key for every node, the error is what
+		// tells whether its node key signature verifies
+		signed := device.TailnetLockError == ""
+		ch <- prometheus.MustNewConstMetric(devicesTailnetLockSignedDesc, prometheus.GaugeValue, boolAsFloat(signed),
+			device.ID, device.Name, device.Hostname, device.OS, device.User)
+		if device.TailnetLockError != "" {
+			ch <- prometheus.MustNewConstMetric(devicesTailnetLockErrorDesc, prometheus.GaugeValue, 1,
+				device.ID, device.Name, device.Hostname, device.OS, device.User, device.TailnetLockError)
+		}
+
 		// Timestamp metrics
 		if !device.LastSeen.IsZero() {
 			ch <- prometheus.MustNewConstMetric(devicesLastSeenDesc, prometheus.GaugeValue, float64(device.LastSeen.Unix()),
@@ -216,6 +363,10 @@ func (c TailscaleDevicesCollector) Update(
 			ch <- prometheus.MustNewConstMetric(devicesExpiresDesc, prometheus.GaugeValue, float64(device.Expires.Unix()),
 				device.ID, device.Name, device.Hostname, device.OS, device.User)
 		}
+		if expires {
+			ch <- prometheus.MustNewConstMetric(devicesExpiresInDesc, prometheus.GaugeValue, device.Expires.Sub(now).Seconds(),
+				device.ID, device.Name, device.Hostname, device.OS, device.User)
+		}
 		if !device.Created.IsZero() {
 			ch <- prometheus.MustNewConstMetric(devicesCreatedDesc, prometheus.GaugeValue, float64(device.Created.Unix()),
 				device.ID, device.Name, device.Hostname, device.OS, device.User)
@@ -253,5 +404,76 @@ func (c TailscaleDevicesCollector) Update(
 			}
 		}
 	}
+
+	ch <- prometheus.MustNewConstMetric(tailnetDevicesTailnetLockUnsignedDesc, prometheus.GaugeValue, float64(unsigned))
+
+	for i, window := range expiring.windows {
+		ch <- prometheus.MustNewConstMetric(tailnetDevicesExpiringDesc, prometheus.GaugeValue,
+			float64(expiring.counts[i]), formatWindow(window))
+	}
+
+	for key, count := range aggregates {
+		ch <- prometheus.MustNewConstMetric(tailnetDevicesDesc, prometheus.GaugeValue, float64(count),
+			key.os,
+			strconv.FormatBool(key.online),
+			strconv.FormatBool(key.authorized),
+			strconv.FormatBool(key.external),
+			strconv.FormatBool(key.updateAvailable),
+			strconv.FormatBool(key.ephemeral),
+		)
+	}
+
+	changes := c.changes.observe(inventory, func(id string, prev, cur *deviceState) []string {
+		return c.diff(ctx, id, prev, cur)
+	})
+	for kind, desc := range devicesChangeDescs {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, changes[kind])
+	}
 	return nil
 }
+
+// diff logs and classifies the change of a device between two polls.
+func (c *TailscaleDevicesCollector) diff(
+	ctx context.Context,
+	id string,
+	prev, cur *deviceState,
+) []string {
+	switch {
+	case prev == nil:
+		c.log.InfoContext(ctx, "Device added",
+			"id", id, "name", cur.Name, "hostname", cur.Hostname, "user", cur.User)
+		return []string{changeAdded}
+	case cur == nil:
+		c.log.InfoContext(ctx, "Device removed",
+			"id", id, "name", prev.Name, "hostname", prev.Hostname, "user", prev.User)
+		return []string{changeRemoved}
+	}
+
+	var kinds []string
+	if prev.Name != cur.Name {
+		c.log.InfoContext(ctx, "Device renamed",
+			"id", id, "old_name", prev.Name, "name", cur.Name)
+		kinds = append(kinds, deviceChangeRenamed)
+	}
+	if prev.MachineKey != cur.MachineKey || prev.NodeKey != cur.NodeKey {
+		c.log.InfoContext(ctx, "Device key rotated",
+			"id", id, "name", cur.Name,
+			"machine_key_changed", prev.MachineKey != cur.MachineKey,
+			"node_key_changed", prev.NodeKey != cur.NodeKey)
+		kinds = append(kinds, deviceChangeKeyRotated)
+	}
+	return kinds
+}
+
+// isOnline reports whether a device last seen at lastSeen counts as online.
+func isOnline(lastSeen time.Time) bool {
+	return time.Since(lastSeen) < 5*time.Minute
+}
+
+func (c *TailscaleDevicesCollector) saveState() (json.RawMessage, error) {
+	return c.changes.saveState()
+}
+
+func (c *TailscaleDevicesCollector) loadState(state json.RawMessage) error {
+	return c.changes.loadState(state)
+}
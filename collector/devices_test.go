@@ -14,11 +14,14 @@ import (
 
 func TestTailscaleDevicesCollector_Update(t *testing.T) {
 	logger := slog.Default()
+	setTimeNow(t, time.Unix(1640390400, 0))
 
 	tests := []struct {
 		name            string
 		mockClient      *MockTailscaleClient
 		expectedMetrics string
+		metricNames     []string
+		aggregateOnly   bool
 		expectError     bool
 	}{
 		{
@@ -52,8 +55,9 @@ func TestTailscaleDevicesCollector_Update(t *testing.T) {
 							Expires: tailscale.Time{
 								Time: time.Unix(1640995200, 0),
 							},
-							MachineKey: "mkey:abcd1234",
-							NodeKey:    "nodekey:efgh5678",
+							MachineKey:     "mkey:abcd1234",
+							NodeKey:        "nodekey:efgh5678",
+							TailnetLockKey: "tlpub:abcd1234",
 							ClientConnectivity: &tailscale.ClientConnectivity{
 								DERPLatency: map[string]tailscale.DERPRegion{
 									"nyc": {LatencyMilliseconds: 50},
@@ -99,6 +103,9 @@ tailscale_devices_last_seen_timestamp{hostname="device-one",id="device-123",name
 # TYPE tailscale_devices_latency_ms gauge
 tailscale_devices_latency_ms{derp_region="lax",hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 100
 tailscale_devices_latency_ms{derp_region="nyc",hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 50
+# HELP tailscale_devices_expires_in_seconds Seconds until the device key expires, negative once expired. Not exported for devices with key expiry disabled
+# TYPE tailscale_devices_expires_in_seconds gauge
+tailscale_devices_expires_in_seconds{hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 604800
 # HELP tailscale_devices_expires_timestamp Unix timestamp when device key expires
 # TYPE tailscale_devices_expires_timestamp gauge
 tailscale_devices_expires_timestamp{hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 1.6409952e+09
@@ -111,6 +118,130 @@ tailscale_devices_routes_advertised{hostname="device-one",id="device-123",name="
 # HELP tailscale_devices_routes_enabled Number of routes enabled for device
 # TYPE tailscale_devices_routes_enabled gauge
 tailscale_devices_routes_enabled{hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 1
+# HELP tailscale_devices_tailnet_lock_signed Whether the node key of device is signed by Tailnet Lock, i.e. it has no Tailnet Lock error. Always 1 with Tailnet Lock disabled
+# TYPE tailscale_devices_tailnet_lock_signed gauge
+tailscale_devices_tailnet_lock_signed{hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 1
+# HELP tailscale_tailnet_devices_tailnet_lock_unsigned Number of devices locked out because their node key is not signed by Tailnet Lock, i.e. with a Tailnet Lock error
+# TYPE tailscale_tailnet_devices_tailnet_lock_unsigned gauge
+tailscale_tailnet_devices_tailnet_lock_unsigned 0
+# HELP tailscale_tailnet_devices Number of devices in the tailnet
+# TYPE tailscale_tailnet_devices gauge
+tailscale_tailnet_devices{authorized="true",ephemeral="false",external="false",online="false",os="linux",update_available="false"} 1
+# HELP tailscale_devices_added_total Number of devices added to the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_added_total counter
+tailscale_devices_added_total 0
+# HELP tailscale_devices_removed_total Number of devices removed from the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_removed_total counter
+tailscale_devices_removed_total 0
+# HELP tailscale_devices_renamed_total Number of device renames since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_renamed_total counter
+tailscale_devices_renamed_total 0
+# HELP tailscale_devices_key_rotated_total Number of device machine or node key changes since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_key_rotated_total counter
+tailscale_devices_key_rotated_total 0
+`,
+			expectError: false,
+		},
+		{
+			name: "signed and unsigned devices",
+			mockClient: &MockTailscaleClient{
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{
+						{
+							ID:             "device-123",
+							Name:           "Device One",
+							Hostname:       "device-one",
+							User:           "user-456",
+							OS:             "linux",
+							TailnetLockKey: "tlpub:abcd1234",
+						},
+						{
+							ID:             "device-456",
+							Name:           "Device Three",
+							Hostname:       "device-three",
+							User:           "user-456",
+							OS:             "linux",
+							TailnetLockKey: "tlpub:ijkl9012",
+						},
+						{
+							ID:               "device-789",
+							Name:             "Device Two",
+							Hostname:         "device-two",
+							User:             "user-456",
+							OS:               "linux",
+							TailnetLockKey:   "tlpub:efgh5678",
+							TailnetLockError: "node is not signed",
+						},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_devices_tailnet_lock_signed Whether the node key of device is signed by Tailnet Lock, i.e. it has no Tailnet Lock error. Always 1 with Tailnet Lock disabled
+# TYPE tailscale_devices_tailnet_lock_signed gauge
+tailscale_devices_tailnet_lock_signed{hostname="device-one",id="device-123",name="Device One",os="linux",user="user-456"} 1
+tailscale_devices_tailnet_lock_signed{hostname="device-three",id="device-456",name="Device Three",os="linux",user="user-456"} 1
+tailscale_devices_tailnet_lock_signed{hostname="device-two",id="device-789",name="Device Two",os="linux",user="user-456"} 0
+# HELP tailscale_devices_tailnet_lock_error Tailnet Lock error reported for device
+# TYPE tailscale_devices_tailnet_lock_error gauge
+tailscale_devices_tailnet_lock_error{error="node is not signed",hostname="device-two",id="device-789",name="Device Two",os="linux",user="user-456"} 1
+# HELP tailscale_tailnet_devices_tailnet_lock_unsigned Number of devices locked out because their node key is not signed by Tailnet Lock, i.e. with a Tailnet Lock error
+# TYPE tailscale_tailnet_devices_tailnet_lock_unsigned gauge
+tailscale_tailnet_devices_tailnet_lock_unsigned 1
+`,
+			metricNames: []string{
+				"tailscale_devices_tailnet_lock_signed",
+				"tailscale_devices_tailnet_lock_error",
+				"tailscale_tailnet_devices_tailnet_lock_unsigned",
+			},
+			expectError: false,
+		},
+		{
+			name: "aggregate only",
+			mockClient: &MockTailscaleClient{
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{
+						{
+							ID:         "device-1",
+							OS:         "linux",
+							Authorized: true,
+							LastSeen:   tailscale.Time{Time: time.Now()},
+						},
+						{
+							ID:         "device-2",
+							OS:         "linux",
+							Authorized: true,
+							LastSeen:   tailscale.Time{Time: time.Now()},
+						},
+						{
+							ID:               "device-3",
+							OS:               "windows",
+							IsEphemeral:      true,
+							TailnetLockError: "node is not signed",
+						},
+					},
+				},
+			},
+			aggregateOnly: true,
+			expectedMetrics: `
+# HELP tailscale_tailnet_devices_tailnet_lock_unsigned Number of devices locked out because their node key is not signed by Tailnet Lock, i.e. with a Tailnet Lock error
+# TYPE tailscale_tailnet_devices_tailnet_lock_unsigned gauge
+tailscale_tailnet_devices_tailnet_lock_unsigned 1
+# HELP tailscale_tailnet_devices Number of devices in the tailnet
+# TYPE tailscale_tailnet_devices gauge
+tailscale_tailnet_devices{authorized="false",ephemeral="true",external="false",online="false",os="windows",update_available="false"} 1
+tailscale_tailnet_devices{authorized="true",ephemeral="false",external="false",online="true",os="linux",update_available="false"} 2
+# HELP tailscale_devices_added_total Number of devices added to the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_added_total counter
+tailscale_devices_added_total 0
+# HELP tailscale_devices_removed_total Number of devices removed from the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_removed_total counter
+tailscale_devices_removed_total 0
+# HELP tailscale_devices_renamed_total Number of device renames since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_renamed_total counter
+tailscale_devices_renamed_total 0
+# HELP tailscale_devices_key_rotated_total Number of device machine or node key changes since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_devices_key_rotated_total counter
+tailscale_devices_key_rotated_total 0
 `,
 			expectError: false,
 		},
@@ -119,12 +250,13 @@ tailscale_devices_routes_enabled{hostname="device-one",id="device-123",name="Dev
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			collector := &TailscaleDevicesCollector{
-				log: logger,
+				log:           logger,
+				aggregateOnly: tt.aggregateOnly,
 			}
 
-			// Buffer must be >= number of metrics emitted per device (currently 12) to avoid blocking Update.
-			// Using a generous buffer to be resilient to future additions.
-			ch := make(chan prometheus.Metric, 32)
+			// Buffer must hold every metric emitted for all devices of a case, or
+			// Update blocks.
+			ch := make(chan prometheus.Metric, 128)
 			ctx := context.Background()
 
 			err := collector.Update(ctx, tt.mockClient, ch)
@@ -156,9 +288,60 @@ tailscale_devices_routes_enabled{hostname="device-one",id="device-123",name="Dev
 			reg.MustRegister(tempCollector)
 
 			// Compare the metrics
-			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics), tt.metricNames...); err != nil {
 				t.Errorf("metrics mismatch: %v", err)
 			}
 		})
 	}
 }
+
+func TestTailscaleDevicesCollector_Expiring(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	setTimeNow(t, now)
+
+	day := 24 * time.Hour
+	expires := func(d time.Duration) tailscale.Time { return tailscale.Time{Time: now.Add(d)} }
+	client := &MockTailscaleClient{
+		devicesClient: &MockDevicesClient{
+			devices: []tailscale.Device{
+				{ID: "soon", Expires: expires(2 * day)},
+				{ID: "later", Expires: expires(20 * day)},
+				{ID: "expired", Expires: expires(-day)},
+				{ID: "disabled", Expires: expires(day), KeyExpiryDisabled: true},
+				{ID: "no-expiry"},
+			},
+		},
+	}
+
+	collector := &TailscaleDevicesCollector{
+		log:           slog.Default(),
+		aggregateOnly: true,
+		expiryWindows: DefaultExpiryWindows(),
+	}
+	ch := make(chan prometheus.Metric, 32)
+	if err := collector.Update(context.Background(), client, ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for metric := range ch {
+		metrics = append(metrics, metric)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(&TestMetricCollector{metrics: metrics})
+
+	expected := `
+# HELP tailscale_tailnet_devices_expiring Number of devices whose key expires within the window, excluding devices with key expiry disabled
+# TYPE tailscale_tailnet_devices_expiring gauge
+tailscale_tailnet_devices_expiring{window="30d"} 2
+tailscale_tailnet_devices_expiring{window="7d"} 1
+`
+	if err := testutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"tailscale_tailnet_devices_expiring",
+	); err != nil {
+		t.Errorf("metrics mismatch: %v", err)
+	}
+}
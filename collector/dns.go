@@ -22,6 +22,24 @@ var (
 		"Tailscale Magic DNS configuration.",
 		[]string{},
 	)
+	dnsSplitDNSDesc = newDesc(
+		dnsSubsystem,
+		"split_dns_info",
+		"Tailscale split DNS routes.",
+		[]string{"domain", "nameserver"},
+	)
+	dnsSearchPathsDesc = newDesc(
+		dnsSubsystem,
+		"search_paths_info",
+		"Tailscale DNS search paths configuration.",
+		[]string{"search_path"},
+	)
+	dnsOverrideLocalDNSDesc = newDesc(
+		dnsSubsystem,
+		"override_local_dns",
+		"Whether Tailscale DNS settings override the local DNS settings of devices.",
+		[]string{},
+	)
 )
 
 type TailscaleDNSCollector struct {
@@ -83,5 +101,68 @@ func (c TailscaleDNSCollector) Update(
 		boolAsFloat(magicDns.MagicDNS),
 	)
 
+	splitDNS, err := client.DNS().SplitDNS(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale split dns",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	// Split DNS metrics
+	for domain, nameservers := range splitDNS {
+		for _, ns := range nameservers {
+			ch <- prometheus.MustNewConstMetric(
+				dnsSplitDNSDesc,
+				prometheus.GaugeValue,
+				1,
+				domain,
+				ns,
+			)
+		}
+	}
+
+	searchPaths, err := client.DNS().SearchPaths(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale dns search paths",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	for _, searchPath := range searchPaths {
+		ch <- prometheus.MustNewConstMetric(
+			dnsSearchPathsDesc,
+			prometheus.GaugeValue,
+			1,
+			searchPath,
+		)
+	}
+
+	// The configuration endpoint is still in alpha, so don't fail the
+	// collector if it is unavailable.
+	configuration, err := client.DNS().Configuration(ctx)
+	if err != nil {
+		c.log.DebugContext(
+			ctx,
+			"Error getting Tailscale dns configuration",
+			"error",
+			err.Error(),
+		)
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		dnsOverrideLocalDNSDesc,
+		prometheus.GaugeValue,
+		boolAsFloat(configuration.Preferences.OverrideLocalDNS),
+	)
+
 	return nil
 }
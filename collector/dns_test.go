@@ -2,6 +2,7 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"strings"
 	"testing"
@@ -28,6 +29,11 @@ func TestTailscaleDNSCollector_Update(t *testing.T) {
 					preferences: &tailscale.DNSPreferences{
 						MagicDNS: true,
 					},
+					splitDNS: tailscale.SplitDNSResponse{
+						"corp.example.com": {"10.0.0.53", "10.0.1.53"},
+					},
+					searchPaths:   []string{"corp.example.com"},
+					configuration: &DNSConfiguration{},
 				},
 			},
 			expectedMetrics: `
@@ -39,9 +45,44 @@ tailscale_dns_nameservers_info{nameserver="8.8.8.8"} 1
 # HELP tailscale_dns_magic_dns Tailscale Magic DNS configuration.
 # TYPE tailscale_dns_magic_dns gauge
 tailscale_dns_magic_dns 1
+# HELP tailscale_dns_split_dns_info Tailscale split DNS routes.
+# TYPE tailscale_dns_split_dns_info gauge
+tailscale_dns_split_dns_info{domain="corp.example.com",nameserver="10.0.0.53"} 1
+tailscale_dns_split_dns_info{domain="corp.example.com",nameserver="10.0.1.53"} 1
+# HELP tailscale_dns_search_paths_info Tailscale DNS search paths configuration.
+# TYPE tailscale_dns_search_paths_info gauge
+tailscale_dns_search_paths_info{search_path="corp.example.com"} 1
+# HELP tailscale_dns_override_local_dns Whether Tailscale DNS settings override the local DNS settings of devices.
+# TYPE tailscale_dns_override_local_dns gauge
+tailscale_dns_override_local_dns 0
+`,
+			expectError: false,
+		},
+		{
+			name: "dns configuration unavailable",
+			mockClient: &MockTailscaleClient{
+				dnsClient: &MockDNSClient{
+					preferences: &tailscale.DNSPreferences{},
+					configErr:   errors.New("not found (404)"),
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_dns_magic_dns Tailscale Magic DNS configuration.
+# TYPE tailscale_dns_magic_dns gauge
+tailscale_dns_magic_dns 0
 `,
 			expectError: false,
 		},
+		{
+			name: "split dns error",
+			mockClient: &MockTailscaleClient{
+				dnsClient: &MockDNSClient{
+					preferences: &tailscale.DNSPreferences{},
+					splitDNSErr: errors.New("forbidden (403)"),
+				},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -50,7 +91,7 @@ tailscale_dns_magic_dns 1
 				log: logger,
 			}
 
-			ch := make(chan prometheus.Metric, 10)
+			ch := make(chan prometheus.Metric, 16)
 			ctx := context.Background()
 
 			err := collector.Update(ctx, tt.mockClient, ch)
@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/oauth2"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+// Kinds of collector errors.
+const (
+	errorKindAuth        = "auth"
+	errorKindForbidden   = "forbidden"
+	errorKindRateLimited = "rate_limited"
+	errorKindTimeout     = "timeout"
+	errorKindServerError = "server_error"
+	errorKindDecode      = "decode"
+	errorKindNetwork     = "network"
+	errorKindOther       = "other"
+)
+
+// apiErrorStatusRe matches the status code at the end of the message of a
+// tailscale.APIError, which does not export it.
+var apiErrorStatusRe = regexp.MustCompile(`\((\d{3})\)$`)
+
+// apiStatus returns the HTTP status of an API error, or 0 if err is not an
+// API error.
+func apiStatus(err error) int {
+	var apiErr apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.status
+	}
+	var tsErr tailscale.APIError
+	if errors.As(err, &tsErr) {
+		if m := apiErrorStatusRe.FindStringSubmatch(tsErr.Error()); m != nil {
+			status, _ := strconv.Atoi(m[1])
+			return status
+		}
+	}
+	return 0
+}
+
+// isForbidden reports whether err is an API error because the credentials are
+// invalid or lack a scope.
+func isForbidden(err error) bool {
+	status := apiStatus(err)
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// errorKind classifies a collector error, so alerts can tell an unavailable
+// API apart from broken credentials.
+func errorKind(err error) string {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return errorKindAuth
+	}
+
+	switch status := apiStatus(err); {
+	case status == http.StatusUnauthorized:
+		return errorKindAuth
+	case status == http.StatusForbidden:
+		return errorKindForbidden
+	case status == http.StatusTooManyRequests:
+		return errorKindRateLimited
+	case status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		return errorKindTimeout
+	case status >= http.StatusInternalServerError:
+		return errorKindServerError
+	case status != 0:
+		return errorKindOther
+	}
+
+	var netErr net.Error
+	isNetErr := errors.As(err, &netErr)
+	if errors.Is(err, context.DeadlineExceeded) || (isNetErr && netErr.Timeout()) {
+		return errorKindTimeout
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return errorKindDecode
+	}
+
+	if isNetErr {
+		return errorKindNetwork
+	}
+	return errorKindOther
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name: "oauth token",
+			err: &url.Error{
+				Op:  "Get",
+				URL: "https://api.tailscale.com",
+				Err: &oauth2.RetrieveError{},
+			},
+			expected: errorKindAuth,
+		},
+		{
+			name:     "unauthorized",
+			err:      apiError{Message: "unauthorized", status: http.StatusUnauthorized},
+			expected: errorKindAuth,
+		},
+		{
+			name: "forbidden",
+			err: fmt.Errorf(
+				"listing keys: %w",
+				apiError{Message: "forbidden", status: http.StatusForbidden},
+			),
+			expected: errorKindForbidden,
+		},
+		{
+			name:     "rate limited",
+			err:      apiError{Message: "too many requests", status: http.StatusTooManyRequests},
+			expected: errorKindRateLimited,
+		},
+		{
+			name:     "gateway timeout",
+			err:      apiError{Message: "gateway timeout", status: http.StatusGatewayTimeout},
+			expected: errorKindTimeout,
+		},
+		{
+			name:     "server error",
+			err:      apiError{Message: "internal error", status: http.StatusInternalServerError},
+			expected: errorKindServerError,
+		},
+		{
+			name:     "not found",
+			err:      apiError{Message: "not found", status: http.StatusNotFound},
+			expected: errorKindOther,
+		},
+		{
+			name: "context deadline",
+			err: &url.Error{
+				Op:  "Get",
+				URL: "https://api.tailscale.com",
+				Err: context.DeadlineExceeded,
+			},
+			expected: errorKindTimeout,
+		},
+		{
+			name:     "decode",
+			err:      json.Unmarshal([]byte("{"), &struct{}{}),
+			expected: errorKindDecode,
+		},
+		{
+			name: "network",
+			err: &url.Error{
+				Op:  "Get",
+				URL: "https://api.tailscale.com",
+				Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			},
+			expected: errorKindNetwork,
+		},
+		{
+			name:     "other",
+			err:      errors.New("something else"),
+			expected: errorKindOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := errorKind(tt.err); kind != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultExpiryWindows returns the windows devices and keys expiring soon are
+// counted in by default.
+func DefaultExpiryWindows() []time.Duration {
+	return []time.Duration{7 * 24 * time.Hour, 30 * 24 * time.Hour}
+}
+
+// timeNow returns the time expiries are computed relative to, replaced in
+// tests.
+var timeNow = time.Now
+
+// expiringCounts counts the items expiring within each of a set of windows.
+type expiringCounts struct {
+	windows []time.Duration
+	counts  []int
+}
+
+func newExpiringCounts(windows []time.Duration) *expiringCounts {
+	return &expiringCounts{windows: windows, counts: make([]int, len(windows))}
+}
+
+// add counts an item that expires in remaining in every window it falls in.
+// Items that already expired are not counted.
+func (e *expiringCounts) add(remaining time.Duration) {
+	if remaining < 0 {
+		return
+	}
+	for i, window := range e.windows {
+		if remaining <= window {
+			e.counts[i]++
+		}
+	}
+}
+
+// formatWindow formats an expiry window for the window label, in days if it
+// is a whole number of days, e.g. 7d.
+func formatWindow(window time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case window%day == 0:
+		return fmt.Sprintf("%dd", window/day)
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	default:
+		return window.String()
+	}
+}
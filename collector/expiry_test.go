@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatWindow(t *testing.T) {
+	for window, expected := range map[time.Duration]string{
+		7 * 24 * time.Hour: "7d",
+		12 * time.Hour:     "12h",
+		90 * time.Minute:   "1h30m0s",
+	} {
+		if got := formatWindow(window); got != expected {
+			t.Errorf("formatWindow(%v) = %q, expected %q", window, got, expected)
+		}
+	}
+}
+
+// setTimeNow fixes the time expiries are computed relative to at now, until
+// the test ends.
+func setTimeNow(t *testing.T, now time.Time) {
+	t.Helper()
+	prev := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = prev })
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultForbiddenBackoff is how long a collector is disabled by default after
+// the API rejected the credentials, before it is tried again.
+const DefaultForbiddenBackoff = time.Hour
+
+const collectorDisabledForbidden = "forbidden"
+
+// forbiddenCollectors disables collectors whose API calls are rejected with
+// 401 or 403, typically because the OAuth client lacks a scope, and retries
+// them once per backoff instead of on every scrape.
+type forbiddenCollectors struct {
+	mtx     sync.Mutex
+	backoff time.Duration
+	retryAt map[string]time.Time
+}
+
+// skip reports whether the named collector is disabled and not due for a
+// retry yet.
+func (f *forbiddenCollectors) skip(name string, now time.Time) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	retryAt, ok := f.retryAt[name]
+	return ok && now.Before(retryAt)
+}
+
+// observe records the result of running the named collector and reports
+// whether it is disabled. Changes are logged once rather than on every run.
+func (f *forbiddenCollectors) observe(
+	ctx context.Context,
+	logger *slog.Logger,
+	name string,
+	err error,
+	now time.Time,
+) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.retryAt == nil {
+		f.retryAt = make(map[string]time.Time)
+	}
+	_, wasForbidden := f.retryAt[name]
+
+	if err != nil && isForbidden(err) {
+		if !wasForbidden {
+			logger.WarnContext(
+				ctx,
+				"Disabling collector, the credentials are not allowed to access its API",
+				"scopes",
+				collectorScopes[name],
+				"retry_in",
+				f.backoff,
+				"err",
+				err,
+			)
+		}
+		f.retryAt[name] = now.Add(f.backoff)
+		return true
+	}
+
+	if wasForbidden {
+		logger.InfoContext(ctx, "Re-enabling collector")
+		delete(f.retryAt, name)
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestForbiddenCollectors(t *testing.T) {
+	f := &forbiddenCollectors{backoff: time.Hour}
+	ctx := context.Background()
+	now := time.Now()
+	forbidden := apiError{Message: "forbidden", status: http.StatusForbidden}
+
+	if f.observe(ctx, slog.Default(), keysSubsystem, errors.New("timeout"), now) {
+		t.Error("expected other errors not to disable the collector")
+	}
+	if !f.observe(ctx, slog.Default(), keysSubsystem, forbidden, now) {
+		t.Error("expected a 403 to disable the collector")
+	}
+	if !f.skip(keysSubsystem, now.Add(time.Minute)) {
+		t.Error("expected the collector to be skipped during the backoff")
+	}
+	if f.skip(keysSubsystem, now.Add(2*time.Hour)) {
+		t.Error("expected the collector to be retried after the backoff")
+	}
+	if f.observe(ctx, slog.Default(), keysSubsystem, nil, now.Add(2*time.Hour)) {
+		t.Error("expected a success to re-enable the collector")
+	}
+	if f.skip(keysSubsystem, now.Add(2*time.Hour+time.Minute)) {
+		t.Error("expected a re-enabled collector not to be skipped")
+	}
+}
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+const groupsCollector = "groups"
+
+var (
+	policyGroupMembersDesc = newDesc(
+		policySubsystem,
+		"group_members",
+		"Number of members of a policy file group.",
+		[]string{"group"},
+	)
+	policyGroupMemberInfoDesc = newDesc(
+		policySubsystem,
+		"group_member_info",
+		"Membership of a user in a policy file group.",
+		[]string{"group", "login_name"},
+	)
+	policyGroupStaleMembersDesc = newDesc(
+		policySubsystem,
+		"group_stale_member",
+		"Policy file group members that do not exist in the tailnet.",
+		[]string{"group", "login_name"},
+	)
+	policyUngroupedUsersDesc = newDesc(
+		policySubsystem,
+		"ungrouped_user",
+		"Active tailnet members that are not in any policy file group.",
+		[]string{"id", "login_name", "display_name"},
+	)
+)
+
+type TailscaleGroupsCollector struct {
+	log *slog.Logger
+}
+
+func init() {
+	registerCollector(groupsCollector, NewTailscaleGroupsCollector)
+}
+
+func NewTailscaleGroupsCollector(config collectorConfig) (Collector, error) {
+	return &TailscaleGroupsCollector{
+		log: config.logger,
+	}, nil
+}
+
+func (c TailscaleGroupsCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting groups metrics")
+
+	acl, err := client.PolicyFile().Get(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale policy file",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	users, err := client.Users().List(ctx, nil, nil)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale users",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	known := make(map[string]struct{}, len(users))
+	for _, user := range users {
+		known[strings.ToLower(user.LoginName)] = struct{}{}
+	}
+
+	grouped := make(map[string]struct{})
+	for group, members := range acl.Groups {
+		// A member listed twice would otherwise emit duplicate series
+		members = slices.Clone(members)
+		slices.Sort(members)
+		members = slices.Compact(members)
+
+		ch <- prometheus.MustNewConstMetric(
+			policyGroupMembersDesc,
+			prometheus.GaugeValue,
+			float64(len(members)),
+			group,
+		)
+
+		for _, member := range members {
+			ch <- prometheus.MustNewConstMetric(
+				policyGroupMemberInfoDesc,
+				prometheus.GaugeValue,
+				1,
+				group,
+				member,
+			)
+
+			// Autogroups and other selectors are not users, so they can
+			// never be stale.
+			if strings.Contains(member, ":") {
+				continue
+			}
+
+			login := strings.ToLower(member)
+			grouped[login] = struct{}{}
+			if _, ok := known[login]; !ok {
+				ch <- prometheus.MustNewConstMetric(
+					policyGroupStaleMembersDesc,
+					prometheus.GaugeValue,
+					1,
+					group,
+					member,
+				)
+			}
+		}
+	}
+
+	for _, user := range users {
+		if user.Type != tailscale.UserTypeMember || user.Status == tailscale.UserStatusSuspended {
+			continue
+		}
+		if _, ok := grouped[strings.ToLower(user.LoginName)]; ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			policyUngroupedUsersDesc,
+			prometheus.GaugeValue,
+			1,
+			user.ID,
+			user.LoginName,
+			user.DisplayName,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleGroupsCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		expectedMetrics string
+		expectError     bool
+	}{
+		{
+			name: "successful collection with groups",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					acl: &tailscale.ACL{
+						Groups: map[string][]string{
+							"group:eng":   {"alice@example.com", "Gone@example.com"},
+							"group:admin": {"autogroup:admin"},
+						},
+					},
+				},
+				usersClient: &MockUsersClient{
+					users: []tailscale.User{
+						{
+							ID:        "user-1",
+							LoginName: "Alice@example.com",
+							Type:      tailscale.UserTypeMember,
+							Status:    tailscale.UserStatusActive,
+						},
+						{
+							ID:          "user-2",
+							LoginName:   "bob@example.com",
+							DisplayName: "Bob",
+							Type:        tailscale.UserTypeMember,
+							Status:      tailscale.UserStatusIdle,
+						},
+						{
+							ID:        "user-3",
+							LoginName: "carol@example.com",
+							Type:      tailscale.UserTypeMember,
+							Status:    tailscale.UserStatusSuspended,
+						},
+						{
+							ID:        "user-4",
+							LoginName: "guest@example.org",
+							Type:      tailscale.UserTypeShared,
+							Status:    tailscale.UserStatusActive,
+						},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_policy_group_member_info Membership of a user in a policy file group.
+# TYPE tailscale_policy_group_member_info gauge
+tailscale_policy_group_member_info{group="group:admin",login_name="autogroup:admin"} 1
+tailscale_policy_group_member_info{group="group:eng",login_name="Gone@example.com"} 1
+tailscale_policy_group_member_info{group="group:eng",login_name="alice@example.com"} 1
+# HELP tailscale_policy_group_members Number of members of a policy file group.
+# TYPE tailscale_policy_group_members gauge
+tailscale_policy_group_members{group="group:admin"} 1
+tailscale_policy_group_members{group="group:eng"} 2
+# HELP tailscale_policy_group_stale_member Policy file group members that do not exist in the tailnet.
+# TYPE tailscale_policy_group_stale_member gauge
+tailscale_policy_group_stale_member{group="group:eng",login_name="Gone@example.com"} 1
+# HELP tailscale_policy_ungrouped_user Active tailnet members that are not in any policy file group.
+# TYPE tailscale_policy_ungrouped_user gauge
+tailscale_policy_ungrouped_user{display_name="Bob",id="user-2",login_name="bob@example.com"} 1
+`,
+			expectError: false,
+		},
+		{
+			name: "duplicate group member",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					acl: &tailscale.ACL{
+						Groups: map[string][]string{
+							"group:eng": {
+								"gone@example.com",
+								"alice@example.com",
+								"gone@example.com",
+							},
+						},
+					},
+				},
+				usersClient: &MockUsersClient{
+					users: []tailscale.User{
+						{
+							ID:        "user-1",
+							LoginName: "alice@example.com",
+							Type:      tailscale.UserTypeMember,
+							Status:    tailscale.UserStatusActive,
+						},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_policy_group_member_info Membership of a user in a policy file group.
+# TYPE tailscale_policy_group_member_info gauge
+tailscale_policy_group_member_info{group="group:eng",login_name="alice@example.com"} 1
+tailscale_policy_group_member_info{group="group:eng",login_name="gone@example.com"} 1
+# HELP tailscale_policy_group_members Number of members of a policy file group.
+# TYPE tailscale_policy_group_members gauge
+tailscale_policy_group_members{group="group:eng"} 2
+# HELP tailscale_policy_group_stale_member Policy file group members that do not exist in the tailnet.
+# TYPE tailscale_policy_group_stale_member gauge
+tailscale_policy_group_stale_member{group="group:eng",login_name="gone@example.com"} 1
+`,
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscaleGroupsCollector{
+				log: logger,
+			}
+
+			ch := make(chan prometheus.Metric, 32)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// Compare the metrics
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+		})
+	}
+}
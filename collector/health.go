@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CollectorStatus is the outcome of the latest run of a collector.
+type CollectorStatus struct {
+	Enabled     bool       `json:"enabled"`
+	Reason      string     `json:"reason,omitempty"`
+	LastRun     time.Time  `json:"last_run"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// collectorStatuses records the outcome of collector runs for the health
+// endpoints.
+type collectorStatuses struct {
+	mtx      sync.Mutex
+	statuses map[string]CollectorStatus
+}
+
+func (s *collectorStatuses) record(name string, err error, forbidden bool, now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.statuses == nil {
+		s.statuses = make(map[string]CollectorStatus)
+	}
+	status := s.statuses[name]
+	status.Enabled = !forbidden
+	status.Reason = ""
+	if forbidden {
+		status.Reason = collectorDisabledForbidden
+	}
+	status.LastRun = now
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		status.LastSuccess = &now
+	}
+	s.statuses[name] = status
+}
+
+// Status returns the outcome of the latest run of every collector that ran.
+func (t *TailscaleCollector) Status() map[string]CollectorStatus {
+	t.statuses.mtx.Lock()
+	defer t.statuses.mtx.Unlock()
+
+	return maps.Clone(t.statuses.statuses)
+}
+
+// LastSuccess returns when any collector last succeeded, or the zero time if
+// none has yet.
+func (t *TailscaleCollector) LastSuccess() time.Time {
+	var last time.Time
+	for _, status := range t.Status() {
+		if status.LastSuccess != nil && status.LastSuccess.After(last) {
+			last = *status.LastSuccess
+		}
+	}
+	return last
+}
+
+// Refresh runs all collectors and discards the metrics, to update the status
+// and inventory between scrapes.
+func (t *TailscaleCollector) Refresh(ctx context.Context) {
+	ch, wait := discardMetrics()
+	t.collect(ctx, ch)
+	wait()
+}
+
+// discardMetrics returns a channel that drops all metrics sent to it, and a
+// function that closes the channel and waits for it to drain.
+func discardMetrics() (chan<- prometheus.Metric, func()) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return ch, func() {
+		close(ch)
+		<-done
+	}
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+// snapshot holds the data a collector fetched during its latest successful
+// poll, so it can be served without calling the Tailscale API again.
+type snapshot[T any] struct {
+	mtx     sync.RWMutex
+	data    T
+	updated time.Time
+}
+
+func (s *snapshot[T]) set(data T) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.data = data
+	s.updated = time.Now()
+}
+
+// get returns the latest data, and the zero time if nothing was collected yet.
+func (s *snapshot[T]) get() (T, time.Time) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.data, s.updated
+}
+
+// inventoryResponse is the JSON body of the inventory endpoints.
+type inventoryResponse struct {
+	Updated time.Time `json:"updated"`
+	Count   *int      `json:"count,omitempty"`
+	Data    any       `json:"data"`
+}
+
+// filter matches items against the value of a query parameter.
+type filter[T any] func(item T, value string) (bool, error)
+
+// stringFilter matches items whose field equals the query parameter.
+func stringFilter[T any](field func(T) string) filter[T] {
+	return func(item T, value string) (bool, error) {
+		return field(item) == value, nil
+	}
+}
+
+// boolFilter matches items whose field equals the query parameter, parsed as
+// a boolean.
+func boolFilter[T any](field func(T) bool) filter[T] {
+	return func(item T, value string) (bool, error) {
+		want, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, err
+		}
+		return field(item) == want, nil
+	}
+}
+
+var (
+	deviceFilters = map[string]filter[tailscale.Device]{
+		"name": func(d tailscale.Device, value string) (bool, error) {
+			return d.Name == value || d.Hostname == value, nil
+		},
+		"os":   stringFilter(func(d tailscale.Device) string { return d.OS }),
+		"user": stringFilter(func(d tailscale.Device) string { return d.User }),
+		"tag": func(d tailscale.Device, value string) (bool, error) {
+			return slices.Contains(d.Tags, value), nil
+		},
+		"authorized": boolFilter(func(d tailscale.Device) bool { return d.Authorized }),
+		"online": boolFilter(func(d tailscale.Device) bool {
+			return isOnline(d.LastSeen.Time)
+		}),
+		"update_available": boolFilter(func(d tailscale.Device) bool { return d.UpdateAvailable }),
+		"external":         boolFilter(func(d tailscale.Device) bool { return d.IsExternal }),
+	}
+
+	userFilters = map[string]filter[tailscale.User]{
+		"login_name": stringFilter(func(u tailscale.User) string { return u.LoginName }),
+		"role":       stringFilter(func(u tailscale.User) string { return string(u.Role) }),
+		"status":     stringFilter(func(u tailscale.User) string { return string(u.Status) }),
+		"type":       stringFilter(func(u tailscale.User) string { return string(u.Type) }),
+	}
+
+	keyFilters = map[string]filter[tailscale.Key]{
+		"key_type": stringFilter(func(k tailscale.Key) string { return k.KeyType }),
+		"user_id":  stringFilter(func(k tailscale.Key) string { return k.UserID }),
+	}
+)
+
+// InventoryHandler serves the devices, users, keys and tailnet settings seen
+// during the latest collection as JSON, under /devices, /users, /keys and
+// /settings. List endpoints accept query parameters to filter the items.
+func (t *TailscaleCollector) InventoryHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	if c, ok := t.Collectors[devicesSubsystem].(*TailscaleDevicesCollector); ok {
+		mux.HandleFunc("GET /devices", func(w http.ResponseWriter, r *http.Request) {
+			serveList(t, w, r, &c.snapshot, deviceFilters)
+		})
+	}
+	if c, ok := t.Collectors[usersSubsystem].(*TailscaleUsersCollector); ok {
+		mux.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+			serveList(t, w, r, &c.snapshot, userFilters)
+		})
+	}
+	if c, ok := t.Collectors[keysSubsystem].(*TailscaleKeysCollector); ok {
+		mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+			serveList(t, w, r, &c.snapshot, keyFilters)
+		})
+	}
+	if c, ok := t.Collectors[tailnetSettingsSubsystem].(*TailscaleTailnetSettingsCollector); ok {
+		mux.HandleFunc("GET /settings", func(w http.ResponseWriter, r *http.Request) {
+			settings, updated := c.snapshot.get()
+			if updated.IsZero() {
+				t.writeJSONError(
+					w,
+					http.StatusServiceUnavailable,
+					"settings have not been collected yet",
+				)
+				return
+			}
+			t.writeJSON(w, http.StatusOK, inventoryResponse{Updated: updated, Data: settings})
+		})
+	}
+
+	return mux
+}
+
+// serveList writes the items of s that match all filters given in the query
+// string of r.
+func serveList[T any](
+	t *TailscaleCollector,
+	w http.ResponseWriter,
+	r *http.Request,
+	s *snapshot[[]T],
+	filters map[string]filter[T],
+) {
+	items, updated := s.get()
+	if updated.IsZero() {
+		t.writeJSONError(w, http.StatusServiceUnavailable, "inventory has not been collected yet")
+		return
+	}
+
+	query := r.URL.Query()
+	for param := range query {
+		if _, ok := filters[param]; !ok {
+			t.writeJSONError(w, http.StatusBadRequest, "unknown filter "+strconv.Quote(param))
+			return
+		}
+	}
+
+	matches := make([]T, 0, len(items))
+items:
+	for _, item := range items {
+		for param, values := range query {
+			for _, value := range values {
+				ok, err := filters[param](item, value)
+				if err != nil {
+					t.writeJSONError(w, http.StatusBadRequest, "invalid value for filter "+strconv.Quote(param))
+					return
+				}
+				if !ok {
+					continue items
+				}
+			}
+		}
+		matches = append(matches, item)
+	}
+
+	count := len(matches)
+	t.writeJSON(w, http.StatusOK, inventoryResponse{
+		Updated: updated,
+		Count:   &count,
+		Data:    matches,
+	})
+}
+
+func (t *TailscaleCollector) writeJSONError(w http.ResponseWriter, status int, message string) {
+	t.writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (t *TailscaleCollector) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		t.logger.Error("Error writing response", "err", err)
+	}
+}
@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleCollector_InventoryHandler(t *testing.T) {
+	devices := &TailscaleDevicesCollector{log: slog.Default(), aggregateOnly: true}
+	collector := &TailscaleCollector{
+		logger: slog.Default(),
+		Collectors: map[string]Collector{
+			devicesSubsystem: devices,
+		},
+	}
+	handler := collector.InventoryHandler()
+
+	get := func(target string) (int, inventoryResponse) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var res inventoryResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+		}
+		return rec.Code, res
+	}
+
+	if code, _ := get("/devices"); code != http.StatusServiceUnavailable {
+		t.Errorf(
+			"expected status %d before the first collection, got %d",
+			http.StatusServiceUnavailable,
+			code,
+		)
+	}
+
+	client := &MockTailscaleClient{
+		devicesClient: &MockDevicesClient{devices: []tailscale.Device{
+			{
+				ID:         "device-1",
+				Name:       "one",
+				OS:         "linux",
+				Tags:       []string{"tag:server"},
+				Authorized: true,
+			},
+			{ID: "device-2", Name: "two", OS: "linux", Authorized: false},
+			{ID: "device-3", Name: "three", OS: "macOS", Authorized: true},
+		}},
+	}
+	ch := make(chan prometheus.Metric, 32)
+	if err := devices.Update(context.Background(), client, ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(ch)
+
+	tests := []struct {
+		name     string
+		target   string
+		expected int
+		count    int
+	}{
+		{name: "all devices", target: "/devices", expected: http.StatusOK, count: 3},
+		{name: "by os", target: "/devices?os=linux", expected: http.StatusOK, count: 2},
+		{
+			name:     "by os and authorized",
+			target:   "/devices?os=linux&authorized=true",
+			expected: http.StatusOK,
+			count:    1,
+		},
+		{name: "by tag", target: "/devices?tag=tag:server", expected: http.StatusOK, count: 1},
+		{
+			name:     "invalid bool",
+			target:   "/devices?authorized=maybe",
+			expected: http.StatusBadRequest,
+		},
+		{name: "unknown filter", target: "/devices?color=red", expected: http.StatusBadRequest},
+		{name: "collector disabled", target: "/users", expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, res := get(tt.target)
+			if code != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, code)
+			}
+			if code == http.StatusOK && (res.Count == nil || *res.Count != tt.count) {
+				t.Errorf("expected %d devices, got %v", tt.count, res.Count)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	invitesSubsystem = "invites"
+
+	// DefaultDeviceInvitesInterval is the default minimum interval between
+	// sweeps of the device share invites.
+	DefaultDeviceInvitesInterval = 15 * time.Minute
+)
+
+var (
+	invitesUserDesc = newDesc(
+		invitesSubsystem,
+		"user_invites",
+		"Number of user invites to the tailnet.",
+		[]string{"state", "role", "inviter_id"},
+	)
+	invitesDeviceDesc = newDesc(
+		invitesSubsystem,
+		"device_invites",
+		"Number of share invites for a device.",
+		[]string{"state", "device_id", "device_name", "sharer_id"},
+	)
+	invitesDeviceOldestPendingAgeDesc = newDesc(
+		invitesSubsystem,
+		"device_invites_oldest_pending_age_seconds",
+		"Seconds since the oldest pending device share invite was created.",
+		[]string{},
+	)
+	invitesUserOldestEmailAgeDesc = newDesc(
+		invitesSubsystem,
+		"user_invites_oldest_email_age_seconds",
+		"Seconds since the email of the pending user invite emailed longest ago was last sent. The API does not expose when user invites were created.",
+		[]string{},
+	)
+)
+
+const (
+	inviteStatePending  = "pending"
+	inviteStateAccepted = "accepted"
+)
+
+// UserInvite is an invite for a user to join the tailnet. The API only lists
+// invites that have not been accepted yet.
+type UserInvite struct {
+	ID              apiID     `json:"id"`
+	Role            string    `json:"role"`
+	InviterID       apiID     `json:"inviterId"`
+	Email           string    `json:"email"`
+	LastEmailSentAt time.Time `json:"lastEmailSentAt"`
+}
+
+// DeviceInvite is an invite to share a device with another tailnet.
+type DeviceInvite struct {
+	ID              apiID     `json:"id"`
+	Created         time.Time `json:"created"`
+	DeviceID        apiID     `json:"deviceId"`
+	SharerID        apiID     `json:"sharerId"`
+	MultiUse        bool      `json:"multiUse"`
+	AllowExitNode   bool      `json:"allowExitNode"`
+	Email           string    `json:"email"`
+	LastEmailSentAt time.Time `json:"lastEmailSentAt"`
+	Accepted        bool      `json:"accepted"`
+}
+
+// invitesResource implements InvitesAPI on top of the Tailscale API.
+type invitesResource struct {
+	api *apiClient
+}
+
+func (r *invitesResource) UserInvites(ctx context.Context) ([]UserInvite, error) {
+	var invites []UserInvite
+	if err := r.api.getTailnet(ctx, &invites, "user-invites"); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (r *invitesResource) DeviceInvites(
+	ctx context.Context,
+	deviceID string,
+) ([]DeviceInvite, error) {
+	var invites []DeviceInvite
+	if err := r.api.get(ctx, &invites, "device", deviceID, "device-invites"); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// deviceInviteCount is the number of share invites of a device with the same
+// state and sharer.
+type deviceInviteCount struct {
+	state      string
+	deviceID   string
+	deviceName string
+	sharerID   string
+	count      int
+}
+
+type TailscaleInvitesCollector struct {
+	log      *slog.Logger
+	interval time.Duration
+
+	mtx                sync.Mutex
+	lastSweep          time.Time
+	deviceInvites      []deviceInviteCount
+	oldestDeviceInvite time.Time
+}
+
+func init() {
+	registerCollector(invitesSubsystem, NewTailscaleInvitesCollector)
+}
+
+func NewTailscaleInvitesCollector(config collectorConfig) (Collector, error) {
+	return &TailscaleInvitesCollector{
+		log:      config.logger,
+		interval: config.deviceInvitesInterval,
+	}, nil
+}
+
+func (c *TailscaleInvitesCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting invites metrics")
+
+	userInvites, err := client.Invites().UserInvites(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale user invites",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	type userInviteKey struct{ role, inviterID string }
+	userCounts := make(map[userInviteKey]int)
+	var lastEmailSent time.Time
+	for _, invite := range userInvites {
+		userCounts[userInviteKey{invite.Role, string(invite.InviterID)}]++
+		lastEmailSent = oldest(lastEmailSent, invite.LastEmailSentAt)
+	}
+	for key, count := range userCounts {
+		ch <- prometheus.MustNewConstMetric(
+			invitesUserDesc, prometheus.GaugeValue, float64(count),
+			inviteStatePending, key.role, key.inviterID,
+		)
+	}
+	if !lastEmailSent.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			invitesUserOldestEmailAgeDesc, prometheus.GaugeValue,
+			time.Since(lastEmailSent).Seconds(),
+		)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// Device invites are listed per device, so only sweep them once the
+	// interval has passed and serve the cached counts in between.
+	if c.lastSweep.IsZero() || time.Since(c.lastSweep) >= c.interval {
+		if err := c.sweep(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	for _, invite := range c.deviceInvites {
+		ch <- prometheus.MustNewConstMetric(
+			invitesDeviceDesc, prometheus.GaugeValue, float64(invite.count),
+			invite.state, invite.deviceID, invite.deviceName, invite.sharerID,
+		)
+	}
+	if !c.oldestDeviceInvite.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			invitesDeviceOldestPendingAgeDesc, prometheus.GaugeValue,
+			time.Since(c.oldestDeviceInvite).Seconds(),
+		)
+	}
+
+	return nil
+}
+
+// sweep lists the share invites of every device. Devices deleted since the
+// device list was fetched are skipped. Any other error fails the sweep, so the
+// next scrape retries it instead of serving partial counts for the interval.
+func (c *TailscaleInvitesCollector) sweep(ctx context.Context, client TailscaleClient) error {
+	devices, err := client.Devices().List(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale devices",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	type deviceInviteKey struct{ state, sharerID string }
+	var counts []deviceInviteCount
+	var oldestDeviceInvite time.Time
+	for _, device := range devices {
+		invites, err := client.Invites().DeviceInvites(ctx, device.ID)
+		if apiStatus(err) == http.StatusNotFound {
+			c.log.WarnContext(
+				ctx,
+				"Skipping device invites of deleted device",
+				"device_id",
+				device.ID,
+				"error",
+				err.Error(),
+			)
+			continue
+		}
+		if err != nil {
+			c.log.ErrorContext(
+				ctx,
+				"Error getting Tailscale device invites",
+				"device_id",
+				device.ID,
+				"error",
+				err.Error(),
+			)
+			return err
+		}
+
+		deviceCounts := make(map[deviceInviteKey]int)
+		for _, invite := range invites {
+			state := inviteStatePending
+			if invite.Accepted {
+				state = inviteStateAccepted
+			} else {
+				oldestDeviceInvite = oldest(oldestDeviceInvite, invite.Created)
+			}
+			deviceCounts[deviceInviteKey{state, string(invite.SharerID)}]++
+		}
+		for key, count := range deviceCounts {
+			counts = append(counts, deviceInviteCount{
+				state:      key.state,
+				deviceID:   device.ID,
+				deviceName: device.Name,
+				sharerID:   key.sharerID,
+				count:      count,
+			})
+		}
+	}
+
+	c.deviceInvites = counts
+	c.oldestDeviceInvite = oldestDeviceInvite
+	c.lastSweep = time.Now()
+
+	return nil
+}
+
+// oldest returns the earlier of two times, ignoring zero times.
+func oldest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
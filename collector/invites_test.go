@@ -0,0 +1,288 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleInvitesCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		expectedMetrics string
+		expectedAges    int
+		expectError     bool
+	}{
+		{
+			name: "successful collection with invites",
+			mockClient: &MockTailscaleClient{
+				invitesClient: &MockInvitesClient{
+					userInvites: []UserInvite{
+						{ID: "1", Role: "member", InviterID: "100", LastEmailSentAt: time.Now()},
+						{ID: "2", Role: "member", InviterID: "100", LastEmailSentAt: time.Now()},
+						{ID: "3", Role: "admin", InviterID: "200", LastEmailSentAt: time.Now()},
+					},
+					deviceInvites: map[string][]DeviceInvite{
+						"device-123": {
+							{ID: "10", SharerID: "100", Created: time.Now()},
+							{ID: "11", SharerID: "100", Accepted: true},
+						},
+					},
+				},
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{
+						{ID: "device-123", Name: "db.example.ts.net"},
+						{ID: "device-456", Name: "laptop.example.ts.net"},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_invites_device_invites Number of share invites for a device.
+# TYPE tailscale_invites_device_invites gauge
+tailscale_invites_device_invites{device_id="device-123",device_name="db.example.ts.net",sharer_id="100",state="accepted"} 1
+tailscale_invites_device_invites{device_id="device-123",device_name="db.example.ts.net",sharer_id="100",state="pending"} 1
+# HELP tailscale_invites_user_invites Number of user invites to the tailnet.
+# TYPE tailscale_invites_user_invites gauge
+tailscale_invites_user_invites{inviter_id="100",role="member",state="pending"} 2
+tailscale_invites_user_invites{inviter_id="200",role="admin",state="pending"} 1
+`,
+			expectedAges: 2,
+			expectError:  false,
+		},
+		{
+			name: "device deleted during the sweep is skipped",
+			mockClient: &MockTailscaleClient{
+				invitesClient: &MockInvitesClient{
+					deviceInvites: map[string][]DeviceInvite{
+						"device-456": {
+							{ID: "12", SharerID: "200", Created: time.Now()},
+						},
+					},
+					deviceInvitesErr: map[string]error{
+						"device-123": apiError{Message: "not found", status: http.StatusNotFound},
+					},
+				},
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{
+						{ID: "device-123", Name: "db.example.ts.net"},
+						{ID: "device-456", Name: "laptop.example.ts.net"},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_invites_device_invites Number of share invites for a device.
+# TYPE tailscale_invites_device_invites gauge
+tailscale_invites_device_invites{device_id="device-456",device_name="laptop.example.ts.net",sharer_id="200",state="pending"} 1
+`,
+			expectedAges: 1,
+			expectError:  false,
+		},
+		{
+			name: "device invites forbidden",
+			mockClient: &MockTailscaleClient{
+				invitesClient: &MockInvitesClient{
+					deviceInvitesErr: map[string]error{
+						"device-123": apiError{Message: "forbidden", status: http.StatusForbidden},
+					},
+				},
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{{ID: "device-123"}},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "device invites server error",
+			mockClient: &MockTailscaleClient{
+				invitesClient: &MockInvitesClient{
+					deviceInvitesErr: map[string]error{
+						"device-123": apiError{
+							Message: "internal error",
+							status:  http.StatusInternalServerError,
+						},
+					},
+				},
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{{ID: "device-123"}},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "user invites error",
+			mockClient: &MockTailscaleClient{
+				invitesClient: &MockInvitesClient{
+					userInvitesErr: errors.New("forbidden (403)"),
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscaleInvitesCollector{
+				log:      logger,
+				interval: DefaultDeviceInvitesInterval,
+			}
+
+			ch := make(chan prometheus.Metric, 32)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// Invite ages are not deterministic, so only check they are reported.
+			if err := testutil.GatherAndCompare(
+				reg,
+				strings.NewReader(tt.expectedMetrics),
+				"tailscale_invites_device_invites",
+				"tailscale_invites_user_invites",
+			); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+			if count := testutil.CollectAndCount(
+				tempCollector,
+				"tailscale_invites_device_invites_oldest_pending_age_seconds",
+				"tailscale_invites_user_invites_oldest_email_age_seconds",
+			); count != tt.expectedAges {
+				t.Errorf("expected %d invite age metrics, got %d", tt.expectedAges, count)
+			}
+		})
+	}
+}
+
+func TestTailscaleInvitesCollector_DeviceInvitesInterval(t *testing.T) {
+	invitesClient := &MockInvitesClient{
+		deviceInvites: map[string][]DeviceInvite{
+			"device-123": {{ID: "10", SharerID: "100", Created: time.Now()}},
+		},
+	}
+	client := &MockTailscaleClient{
+		invitesClient: invitesClient,
+		devicesClient: &MockDevicesClient{
+			devices: []tailscale.Device{
+				{ID: "device-123", Name: "db.example.ts.net"},
+				{ID: "device-456", Name: "laptop.example.ts.net"},
+			},
+		},
+	}
+	collector := &TailscaleInvitesCollector{
+		log:      slog.Default(),
+		interval: time.Hour,
+	}
+
+	for range 3 {
+		ch := make(chan prometheus.Metric, 32)
+		if err := collector.Update(context.Background(), client, ch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(ch)
+
+		var metrics []prometheus.Metric
+		for metric := range ch {
+			metrics = append(metrics, metric)
+		}
+		if count := testutil.CollectAndCount(
+			&TestMetricCollector{metrics: metrics},
+			"tailscale_invites_device_invites",
+		); count != 1 {
+			t.Errorf("expected the cached device invites, got %d series", count)
+		}
+	}
+
+	if invitesClient.deviceInvitesCalls != 2 {
+		t.Errorf(
+			"expected a single sweep of 2 devices within the interval, got %d calls",
+			invitesClient.deviceInvitesCalls,
+		)
+	}
+}
+
+func TestTailscaleInvitesCollector_DeviceInvitesRetry(t *testing.T) {
+	invitesClient := &MockInvitesClient{
+		deviceInvites: map[string][]DeviceInvite{
+			"device-123": {{ID: "10", SharerID: "100", Created: time.Now()}},
+		},
+		deviceInvitesErr: map[string]error{
+			"device-123": apiError{
+				Message: "too many requests",
+				status:  http.StatusTooManyRequests,
+			},
+		},
+	}
+	client := &MockTailscaleClient{
+		invitesClient: invitesClient,
+		devicesClient: &MockDevicesClient{
+			devices: []tailscale.Device{{ID: "device-123", Name: "db.example.ts.net"}},
+		},
+	}
+	collector := &TailscaleInvitesCollector{
+		log:      slog.Default(),
+		interval: time.Hour,
+	}
+
+	ch := make(chan prometheus.Metric, 32)
+	if err := collector.Update(context.Background(), client, ch); err == nil {
+		t.Fatal("expected the rate limited sweep to fail")
+	}
+	close(ch)
+
+	// The failed sweep is not cached, so the next scrape retries it
+	invitesClient.deviceInvitesErr = nil
+	ch = make(chan prometheus.Metric, 32)
+	if err := collector.Update(context.Background(), client, ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for metric := range ch {
+		metrics = append(metrics, metric)
+	}
+	if count := testutil.CollectAndCount(
+		&TestMetricCollector{metrics: metrics},
+		"tailscale_invites_device_invites",
+	); count != 1 {
+		t.Errorf("expected the device invites after the retry, got %d series", count)
+	}
+	if invitesClient.deviceInvitesCalls != 2 {
+		t.Errorf("expected the sweep to be retried, got %d calls", invitesClient.deviceInvitesCalls)
+	}
+}
@@ -3,8 +3,11 @@ package collector
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
 )
 
 const keysSubsystem = "keys"
@@ -30,10 +33,27 @@ var (
 		"Timestamp when the key expires.",
 		[]string{"id", "key_type", "user_id"},
 	)
+
+	keysExpiresInDesc = newDesc(
+		keysSubsystem,
+		"expires_in_seconds",
+		"Seconds until the key expires, negative once expired.",
+		[]string{"id", "key_type", "user_id"},
+	)
+
+	tailnetKeysExpiringDesc = newDesc(
+		tailnetSubsystem,
+		"keys_expiring",
+		"Number of keys that expire within the window, excluding revoked and invalid keys.",
+		[]string{"window"},
+	)
 )
 
 type TailscaleKeysCollector struct {
-	log *slog.Logger
+	log           *slog.Logger
+	expiryWindows []time.Duration
+
+	snapshot snapshot[[]tailscale.Key]
 }
 
 func init() {
@@ -42,23 +62,27 @@ func init() {
 
 func NewTailscaleKeysCollector(config collectorConfig) (Collector, error) {
 	return &TailscaleKeysCollector{
-		log: config.logger,
+		log:           config.logger,
+		expiryWindows: config.expiryWindows,
 	}, nil
 }
 
-func (c TailscaleKeysCollector) Update(
+func (c *TailscaleKeysCollector) Update(
 	ctx context.Context,
 	client TailscaleClient,
 	ch chan<- prometheus.Metric,
 ) error {
-	c.log.Debug("Collecting keys metrics")
+	c.log.DebugContext(ctx, "Collecting keys metrics")
 
 	keys, err := client.Keys().List(ctx, true)
 	if err != nil {
-		c.log.Error("Error getting Tailscale keys", "error", err.Error())
+		c.log.ErrorContext(ctx, "Error getting Tailscale keys", "error", err.Error())
 		return err
 	}
+	c.snapshot.set(keys)
 
+	now := timeNow()
+	expiring := newExpiringCounts(c.expiryWindows)
 	for _, key := range keys {
 		ch <- prometheus.MustNewConstMetric(
 			keysInfoDesc, prometheus.GaugeValue, 1,
@@ -74,6 +98,23 @@ func (c TailscaleKeysCollector) Update(
 			keysExpiresDesc, prometheus.GaugeValue, float64(key.Expires.Unix()),
 			key.ID, key.KeyType, key.UserID,
 		)
+
+		if key.Expires.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			keysExpiresInDesc, prometheus.GaugeValue, key.Expires.Sub(now).Seconds(),
+			key.ID, key.KeyType, key.UserID,
+		)
+		if key.Revoked.IsZero() && !key.Invalid {
+			expiring.add(key.Expires.Sub(now))
+		}
+	}
+	for i, window := range expiring.windows {
+		ch <- prometheus.MustNewConstMetric(
+			tailnetKeysExpiringDesc, prometheus.GaugeValue, float64(expiring.counts[i]),
+			formatWindow(window),
+		)
 	}
 
 	return nil
@@ -95,3 +95,56 @@ tailscale_keys_expires_timestamp{id="key-123",key_type="auth",user_id="user-456"
 		})
 	}
 }
+
+func TestTailscaleKeysCollector_Expiring(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	setTimeNow(t, now)
+
+	day := 24 * time.Hour
+	client := &MockTailscaleClient{
+		keysClient: &MockKeysClient{
+			keys: []tailscale.Key{
+				{ID: "soon", KeyType: "auth", Expires: now.Add(3 * day)},
+				{ID: "later", KeyType: "auth", Expires: now.Add(60 * day)},
+				{ID: "revoked", KeyType: "auth", Expires: now.Add(day), Revoked: now},
+			},
+		},
+	}
+
+	collector := &TailscaleKeysCollector{
+		log:           slog.Default(),
+		expiryWindows: DefaultExpiryWindows(),
+	}
+	ch := make(chan prometheus.Metric, 32)
+	if err := collector.Update(context.Background(), client, ch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for metric := range ch {
+		metrics = append(metrics, metric)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(&TestMetricCollector{metrics: metrics})
+
+	expected := `
+# HELP tailscale_keys_expires_in_seconds Seconds until the key expires, negative once expired.
+# TYPE tailscale_keys_expires_in_seconds gauge
+tailscale_keys_expires_in_seconds{id="later",key_type="auth",user_id=""} 5.184e+06
+tailscale_keys_expires_in_seconds{id="revoked",key_type="auth",user_id=""} 86400
+tailscale_keys_expires_in_seconds{id="soon",key_type="auth",user_id=""} 259200
+# HELP tailscale_tailnet_keys_expiring Number of keys that expire within the window, excluding revoked and invalid keys.
+# TYPE tailscale_tailnet_keys_expiring gauge
+tailscale_tailnet_keys_expiring{window="30d"} 1
+tailscale_tailnet_keys_expiring{window="7d"} 1
+`
+	if err := testutil.GatherAndCompare(
+		reg,
+		strings.NewReader(expected),
+		"tailscale_keys_expires_in_seconds",
+		"tailscale_tailnet_keys_expiring",
+	); err != nil {
+		t.Errorf("metrics mismatch: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+)
+
+type scrapeIDKey struct{}
+
+// lastScrapeID numbers scrapes, so their log lines can be correlated.
+var lastScrapeID atomic.Uint64
+
+// withScrapeID returns a context for a new scrape with a unique ID.
+func withScrapeID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scrapeIDKey{}, strconv.FormatUint(lastScrapeID.Add(1), 10))
+}
+
+// logHandler filters records by level and adds the scrape ID from the context
+// to every record logged during a scrape.
+type logHandler struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+// NewLogHandler wraps handler to log records at level and above, with the
+// scrape ID of the context they are logged with. handler should accept all
+// levels, as collectors can log at a lower level than the rest of the
+// exporter, see WithLogLevels.
+func NewLogHandler(handler slog.Handler, level slog.Leveler) slog.Handler {
+	return &logHandler{level: level, handler: handler}
+}
+
+// withLogLevel returns handler logging at level instead of its own level, if
+// it is a handler from NewLogHandler.
+func withLogLevel(handler slog.Handler, level slog.Leveler) slog.Handler {
+	if h, ok := handler.(*logHandler); ok {
+		return &logHandler{level: level, handler: h.handler}
+	}
+	return handler
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := ctx.Value(scrapeIDKey{}).(string); ok {
+		r.AddAttrs(slog.String("scrape_id", id))
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(NewLogHandler(base, slog.LevelInfo)).With("collector", "devices")
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected debug message to be dropped, got %q", buf.String())
+	}
+
+	ctx := withScrapeID(context.Background())
+	logger.InfoContext(ctx, "shown")
+	line := buf.String()
+	if !strings.Contains(line, "collector=devices") || !strings.Contains(line, "scrape_id=") {
+		t.Errorf("expected collector and scrape_id attributes, got %q", line)
+	}
+
+	buf.Reset()
+	debugLogger := slog.New(withLogLevel(logger.Handler(), slog.LevelDebug))
+	debugLogger.Debug("override")
+	if !strings.Contains(buf.String(), "override") ||
+		!strings.Contains(buf.String(), "collector=devices") {
+		t.Errorf(
+			"expected overridden level to log debug message with attributes, got %q",
+			buf.String(),
+		)
+	}
+}
+
+func TestWithScrapeID(t *testing.T) {
+	first := withScrapeID(context.Background()).Value(scrapeIDKey{})
+	second := withScrapeID(context.Background()).Value(scrapeIDKey{})
+	if first == second {
+		t.Errorf("expected unique scrape IDs, got %v twice", first)
+	}
+}
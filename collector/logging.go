@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+const logStreamingSubsystem = "log_streaming"
+
+var (
+	logStreamingConfiguredDesc = newDesc(
+		logStreamingSubsystem,
+		"configured",
+		"Whether log streaming is configured for the log type.",
+		[]string{"log_type"},
+	)
+	logStreamingInfoDesc = newDesc(
+		logStreamingSubsystem,
+		"info",
+		"Log streaming destination for the log type.",
+		[]string{"log_type", "destination_type", "compression_format"},
+	)
+	logStreamingUploadPeriodDesc = newDesc(
+		logStreamingSubsystem,
+		"upload_period_minutes",
+		"Minutes between log uploads to the log streaming destination.",
+		[]string{"log_type"},
+	)
+)
+
+type TailscaleLogStreamingCollector struct {
+	log *slog.Logger
+}
+
+func init() {
+	registerCollector(logStreamingSubsystem, NewTailscaleLogStreamingCollector)
+}
+
+func NewTailscaleLogStreamingCollector(config collectorConfig) (Collector, error) {
+	return &TailscaleLogStreamingCollector{
+		log: config.logger,
+	}, nil
+}
+
+func (c TailscaleLogStreamingCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting log streaming metrics")
+
+	for _, logType := range []tailscale.LogType{tailscale.LogTypeConfig, tailscale.LogTypeNetwork} {
+		configuration, err := client.Logging().LogstreamConfiguration(ctx, logType)
+		if err != nil && !tailscale.IsNotFound(err) {
+			c.log.ErrorContext(
+				ctx,
+				"Error getting Tailscale log streaming configuration",
+				"log_type",
+				logType,
+				"error",
+				err.Error(),
+			)
+			return err
+		}
+
+		// The API responds with 404 when no destination is configured.
+		configured := err == nil && configuration.DestinationType != ""
+		ch <- prometheus.MustNewConstMetric(
+			logStreamingConfiguredDesc, prometheus.GaugeValue, boolAsFloat(configured),
+			string(logType),
+		)
+		if !configured {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			logStreamingInfoDesc, prometheus.GaugeValue, 1,
+			string(logType),
+			string(configuration.DestinationType),
+			string(configuration.CompressionFormat),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			logStreamingUploadPeriodDesc, prometheus.GaugeValue,
+			float64(configuration.UploadPeriodMinutes),
+			string(logType),
+		)
+	}
+
+	return nil
+}
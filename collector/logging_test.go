@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleLogStreamingCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		expectedMetrics string
+		expectError     bool
+	}{
+		{
+			name: "successful collection with log streaming",
+			mockClient: &MockTailscaleClient{
+				loggingClient: &MockLoggingClient{
+					configurations: map[tailscale.LogType]*tailscale.LogstreamConfiguration{
+						tailscale.LogTypeConfig: {
+							LogType:             tailscale.LogTypeConfig,
+							DestinationType:     tailscale.LogstreamS3Endpoint,
+							CompressionFormat:   tailscale.CompressionFormatZstd,
+							UploadPeriodMinutes: 5,
+						},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_log_streaming_configured Whether log streaming is configured for the log type.
+# TYPE tailscale_log_streaming_configured gauge
+tailscale_log_streaming_configured{log_type="configuration"} 1
+tailscale_log_streaming_configured{log_type="network"} 0
+# HELP tailscale_log_streaming_info Log streaming destination for the log type.
+# TYPE tailscale_log_streaming_info gauge
+tailscale_log_streaming_info{compression_format="zstd",destination_type="s3",log_type="configuration"} 1
+# HELP tailscale_log_streaming_upload_period_minutes Minutes between log uploads to the log streaming destination.
+# TYPE tailscale_log_streaming_upload_period_minutes gauge
+tailscale_log_streaming_upload_period_minutes{log_type="configuration"} 5
+`,
+			expectError: false,
+		},
+		{
+			name: "api error",
+			mockClient: &MockTailscaleClient{
+				loggingClient: &MockLoggingClient{
+					errs: map[tailscale.LogType]error{
+						tailscale.LogTypeNetwork: errors.New("forbidden (403)"),
+					},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscaleLogStreamingCollector{
+				log: logger,
+			}
+
+			ch := make(chan prometheus.Metric, 32)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// Compare the metrics
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package collector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tailscale/hujson"
+
+	"tailscale.com/client/tailscale/v2"
+)
+
+const (
+	policySubsystem = "policy"
+
+	// DefaultPolicyTestsInterval is the default minimum interval between
+	// policy test runs.
+	DefaultPolicyTestsInterval = 5 * time.Minute
+)
+
+var (
+	policyValidationSuccessDesc = newDesc(
+		policySubsystem,
+		"validation_success",
+		"Whether the live policy file passed validation, including its own tests.",
+		[]string{},
+	)
+	policyTestPassedDesc = newDesc(
+		policySubsystem,
+		"test_passed",
+		"Whether a policy test passed against the live policy file.",
+		[]string{"test", "src"},
+	)
+	policyTestsDesc = newDesc(
+		policySubsystem,
+		"tests",
+		"Number of policy tests run against the live policy file.",
+		[]string{"source"},
+	)
+	policyTestsLastRunDesc = newDesc(
+		policySubsystem,
+		"tests_last_run_timestamp",
+		"Unix timestamp of the last policy test run.",
+		[]string{},
+	)
+)
+
+// policyValidationError is a policy or test rejected by the validation
+// endpoint, with the errors it reported per user.
+type policyValidationError struct {
+	tailscale.APIError
+}
+
+func (err policyValidationError) Error() string {
+	return fmt.Sprintf("policy validation failed: %s; %v", err.Message, err.Data)
+}
+
+// policyFileResource replaces the validation of *tailscale.PolicyFileResource,
+// which reports rejected policies as plain errors, dropping their details.
+type policyFileResource struct {
+	*tailscale.PolicyFileResource
+	api *apiClient
+}
+
+// Validate validates a HuJSON policy file or a JSON array of tests, returning
+// a policyValidationError if the endpoint rejected it.
+func (r *policyFileResource) Validate(ctx context.Context, acl any) error {
+	body, ok := acl.(string)
+	if !ok {
+		return r.PolicyFileResource.Validate(ctx, acl)
+	}
+
+	var res tailscale.APIError
+	err := r.api.postTailnet(
+		ctx,
+		strings.NewReader(body),
+		"application/hujson",
+		&res,
+		"acl",
+		"validate",
+	)
+	if err != nil {
+		return err
+	}
+	if res.Message != "" || len(res.Data) > 0 {
+		return policyValidationError{APIError: res}
+	}
+	return nil
+}
+
+type policyTestResult struct {
+	test   string
+	src    string
+	passed bool
+}
+
+type TailscalePolicyCollector struct {
+	log       *slog.Logger
+	testsFile string
+	interval  time.Duration
+
+	mtx     sync.Mutex
+	lastRun time.Time
+	valid   bool
+	results []policyTestResult
+}
+
+func init() {
+	registerCollector(policySubsystem, NewTailscalePolicyCollector)
+}
+
+func NewTailscalePolicyCollector(config collectorConfig) (Collector, error) {
+	return &TailscalePolicyCollector{
+		log:       config.logger,
+		testsFile: config.policyTestsFile,
+		interval:  config.policyTestsInterval,
+	}, nil
+}
+
+func (c *TailscalePolicyCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting policy metrics")
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// Every test is a separate API call, so only rerun them once the
+	// interval has passed and serve the cached results in between.
+	if c.lastRun.IsZero() || time.Since(c.lastRun) >= c.interval {
+		if err := c.run(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		policyValidationSuccessDesc,
+		prometheus.GaugeValue,
+		boolAsFloat(c.valid),
+	)
+
+	source := "policy"
+	if c.testsFile != "" {
+		source = "file"
+	}
+	ch <- prometheus.MustNewConstMetric(
+		policyTestsDesc,
+		prometheus.GaugeValue,
+		float64(len(c.results)),
+		source,
+	)
+
+	for _, result := range c.results {
+		ch <- prometheus.MustNewConstMetric(
+			policyTestPassedDesc,
+			prometheus.GaugeValue,
+			boolAsFloat(result.passed),
+			result.test,
+			result.src,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		policyTestsLastRunDesc,
+		prometheus.GaugeValue,
+		float64(c.lastRun.Unix()),
+	)
+
+	return nil
+}
+
+// run validates the live policy file and runs each test against it.
+func (c *TailscalePolicyCollector) run(ctx context.Context, client TailscaleClient) error {
+	raw, err := client.PolicyFile().Raw(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale policy file",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	valid, err := c.validate(ctx, client, raw.HuJSON)
+	if err != nil {
+		return err
+	}
+
+	tests, err := c.tests(raw.HuJSON)
+	if err != nil {
+		c.log.ErrorContext(ctx, "Error loading policy tests", "error", err.Error())
+		return err
+	}
+
+	results := make([]policyTestResult, 0, len(tests))
+	seen := make(map[string]bool, len(tests))
+	for _, test := range tests {
+		id, err := policyTestID(test)
+		if err != nil {
+			return err
+		}
+		// Identical tests have the same result, so run them once
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		body, err := json.Marshal([]tailscale.ACLTest{test})
+		if err != nil {
+			return err
+		}
+
+		src := test.Source
+		if src == "" {
+			src = test.User
+		}
+
+		passed, err := c.validate(ctx, client, string(body))
+		if err != nil {
+			return err
+		}
+		results = append(results, policyTestResult{
+			test:   id,
+			src:    src,
+			passed: passed,
+		})
+	}
+
+	c.valid = valid
+	c.results = results
+	c.lastRun = time.Now()
+
+	return nil
+}
+
+// validate submits body to the validation endpoint. A failed validation is
+// reported as false, while API and transport errors are returned.
+func (c *TailscalePolicyCollector) validate(
+	ctx context.Context,
+	client TailscaleClient,
+	body string,
+) (bool, error) {
+	err := client.PolicyFile().Validate(ctx, body)
+	if err == nil {
+		return true, nil
+	}
+	if isPolicyValidationFailure(err) {
+		c.log.WarnContext(ctx, "Policy validation failed", "error", err.Error())
+		return false, nil
+	}
+
+	c.log.ErrorContext(
+		ctx,
+		"Error validating Tailscale policy file",
+		"error",
+		err.Error(),
+	)
+	return false, err
+}
+
+// tests returns the tests from the configured tests file, falling back to the
+// tests embedded in the policy file.
+func (c *TailscalePolicyCollector) tests(policy string) ([]tailscale.ACLTest, error) {
+	if c.testsFile == "" {
+		var acl tailscale.ACL
+		if err := unmarshalHuJSON([]byte(policy), &acl); err != nil {
+			return nil, fmt.Errorf("parsing policy file: %w", err)
+		}
+		return acl.Tests, nil
+	}
+
+	data, err := os.ReadFile(c.testsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []tailscale.ACLTest
+	if err := unmarshalHuJSON(data, &tests); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", c.testsFile, err)
+	}
+	return tests, nil
+}
+
+// isPolicyValidationFailure reports whether err is a policy or test that was
+// rejected by the validation endpoint, as opposed to an API or transport
+// error.
+func isPolicyValidationFailure(err error) bool {
+	var validationErr policyValidationError
+	return errors.As(err, &validationErr)
+}
+
+// policyTestID identifies a test by a short hash of its source and
+// expectations, so its series stays the same when tests are added, removed
+// or reordered.
+func policyTestID(test tailscale.ACLTest) (string, error) {
+	data, err := json.Marshal(test)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:4]), nil
+}
+
+func unmarshalHuJSON(data []byte, v any) error {
+	data, err := hujson.Standardize(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
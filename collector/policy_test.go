@@ -0,0 +1,190 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+const testPolicy = `{
+	// Comments are allowed in HuJSON.
+	"acls": [{"action": "accept", "src": ["group:eng"], "dst": ["tag:prod:22"]}],
+	"tests": [
+		{"src": "alice@example.com", "accept": ["tag:prod:22"]},
+		{"src": "bob@example.com", "accept": ["tag:prod:22"]},
+	],
+}`
+
+func TestTailscalePolicyCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	testsFile := filepath.Join(t.TempDir(), "tests.hujson")
+	err := os.WriteFile(
+		testsFile,
+		[]byte(`[{"src": "carol@example.com", "deny": ["tag:prod:22"]},]`),
+		0o600,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fails the live policy validation and any test for bob.
+	validateErr := func(acl any) error {
+		body, _ := acl.(string)
+		if body == testPolicy || strings.Contains(body, "bob@example.com") {
+			return policyValidationError{APIError: tailscale.APIError{
+				Message: "test(s) failed",
+				Data:    []tailscale.APIErrorData{{User: "bob@example.com"}},
+			}}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		testsFile       string
+		expectedMetrics string
+		expectError     bool
+	}{
+		{
+			name: "tests from the policy file",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					raw:         &tailscale.RawACL{HuJSON: testPolicy},
+					validateErr: validateErr,
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_policy_test_passed Whether a policy test passed against the live policy file.
+# TYPE tailscale_policy_test_passed gauge
+tailscale_policy_test_passed{src="alice@example.com",test="77d6fccb"} 1
+tailscale_policy_test_passed{src="bob@example.com",test="e2e9ec53"} 0
+# HELP tailscale_policy_tests Number of policy tests run against the live policy file.
+# TYPE tailscale_policy_tests gauge
+tailscale_policy_tests{source="policy"} 2
+# HELP tailscale_policy_validation_success Whether the live policy file passed validation, including its own tests.
+# TYPE tailscale_policy_validation_success gauge
+tailscale_policy_validation_success 0
+`,
+		},
+		{
+			name: "tests from a local file",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					raw: &tailscale.RawACL{HuJSON: testPolicy},
+				},
+			},
+			testsFile: testsFile,
+			expectedMetrics: `
+# HELP tailscale_policy_test_passed Whether a policy test passed against the live policy file.
+# TYPE tailscale_policy_test_passed gauge
+tailscale_policy_test_passed{src="carol@example.com",test="bac77840"} 1
+# HELP tailscale_policy_tests Number of policy tests run against the live policy file.
+# TYPE tailscale_policy_tests gauge
+tailscale_policy_tests{source="file"} 1
+# HELP tailscale_policy_validation_success Whether the live policy file passed validation, including its own tests.
+# TYPE tailscale_policy_validation_success gauge
+tailscale_policy_validation_success 1
+`,
+		},
+		{
+			// Test ids do not depend on the position, and duplicates run once
+			name: "reordered and duplicate tests",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					raw: &tailscale.RawACL{HuJSON: `{"tests": [
+		{"src": "bob@example.com", "accept": ["tag:prod:22"]},
+		{"src": "alice@example.com", "accept": ["tag:prod:22"]},
+		{"src": "alice@example.com", "accept": ["tag:prod:22"]},
+	]}`},
+					validateErr: validateErr,
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_policy_test_passed Whether a policy test passed against the live policy file.
+# TYPE tailscale_policy_test_passed gauge
+tailscale_policy_test_passed{src="alice@example.com",test="77d6fccb"} 1
+tailscale_policy_test_passed{src="bob@example.com",test="e2e9ec53"} 0
+# HELP tailscale_policy_tests Number of policy tests run against the live policy file.
+# TYPE tailscale_policy_tests gauge
+tailscale_policy_tests{source="policy"} 2
+# HELP tailscale_policy_validation_success Whether the live policy file passed validation, including its own tests.
+# TYPE tailscale_policy_validation_success gauge
+tailscale_policy_validation_success 0
+`,
+		},
+		{
+			name: "api error",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					raw: &tailscale.RawACL{HuJSON: testPolicy},
+					validateErr: func(acl any) error {
+						return errors.New("internal server error (500)")
+					},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscalePolicyCollector{
+				log:       logger,
+				testsFile: tt.testsFile,
+				interval:  DefaultPolicyTestsInterval,
+			}
+
+			ch := make(chan prometheus.Metric, 10)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// The last run timestamp is not deterministic, so leave it out.
+			if err := testutil.GatherAndCompare(
+				reg,
+				strings.NewReader(tt.expectedMetrics),
+				"tailscale_policy_test_passed",
+				"tailscale_policy_tests",
+				"tailscale_policy_validation_success",
+			); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+		})
+	}
+}
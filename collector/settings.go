@@ -2,10 +2,15 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
 )
 
 const tailnetSettingsSubsystem = "tailnet_settings"
@@ -33,10 +38,65 @@ var (
 		"Number of days before device key expiry.",
 		[]string{},
 	)
+	tailnetSettingsLastChangedDesc = newDesc(
+		tailnetSettingsSubsystem,
+		"last_changed_timestamp",
+		"Unix timestamp when a change to the Tailscale Tailnet settings was first observed.",
+		[]string{},
+	)
 )
 
+// tailnetSettingsBools pairs each boolean tailnet setting with the gauge it is
+// exported as, next to the info metric.
+var tailnetSettingsBools = []struct {
+	desc  *prometheus.Desc
+	value func(*tailscale.TailnetSettings) bool
+}{
+	{
+		newSettingsBoolDesc("acls_externally_managed_on", "Whether ACLs are managed externally."),
+		func(s *tailscale.TailnetSettings) bool { return s.ACLsExternallyManagedOn },
+	},
+	{
+		newSettingsBoolDesc("devices_approval_on", "Whether device approval is enabled."),
+		func(s *tailscale.TailnetSettings) bool { return s.DevicesApprovalOn },
+	},
+	{
+		newSettingsBoolDesc("devices_auto_updates_on", "Whether device auto-updates are enabled."),
+		func(s *tailscale.TailnetSettings) bool { return s.DevicesAutoUpdatesOn },
+	},
+	{
+		newSettingsBoolDesc("users_approval_on", "Whether user approval is enabled."),
+		func(s *tailscale.TailnetSettings) bool { return s.UsersApprovalOn },
+	},
+	{
+		newSettingsBoolDesc("network_flow_logging_on", "Whether network flow logging is enabled."),
+		func(s *tailscale.TailnetSettings) bool { return s.NetworkFlowLoggingOn },
+	},
+	{
+		newSettingsBoolDesc("regional_routing_on", "Whether regional routing is enabled."),
+		func(s *tailscale.TailnetSettings) bool { return s.RegionalRoutingOn },
+	},
+	{
+		newSettingsBoolDesc(
+			"posture_identity_collection_on",
+			"Whether device posture identity collection is enabled.",
+		),
+		func(s *tailscale.TailnetSettings) bool { return s.PostureIdentityCollectionOn },
+	},
+}
+
+func newSettingsBoolDesc(name, help string) *prometheus.Desc {
+	return newDesc(tailnetSettingsSubsystem, name, help, []string{})
+}
+
 type TailscaleTailnetSettingsCollector struct {
 	log *slog.Logger
+
+	mtx         sync.Mutex
+	previous    *tailscale.TailnetSettings
+	lastChanged time.Time
+
+	snapshot snapshot[*tailscale.TailnetSettings]
 }
 
 func init() {
@@ -49,22 +109,24 @@ func NewTailscaleSettingsCollector(config collectorConfig) (Collector, error) {
 	}, nil
 }
 
-func (c TailscaleTailnetSettingsCollector) Update(
+func (c *TailscaleTailnetSettingsCollector) Update(
 	ctx context.Context,
 	client TailscaleClient,
 	ch chan<- prometheus.Metric,
 ) error {
-	c.log.Debug("Collecting Tailscale Tailnet settings metrics")
+	c.log.DebugContext(ctx, "Collecting Tailscale Tailnet settings metrics")
 
 	settings, err := client.TailnetSettings().Get(ctx)
 	if err != nil {
-		c.log.Error(
+		c.log.ErrorContext(
+			ctx,
 			"Error getting Tailscale Tailnet settings",
 			"error",
 			err.Error(),
 		)
 		return err
 	}
+	c.snapshot.set(settings)
 
 	ch <- prometheus.MustNewConstMetric(
 		tailnetSettingsInfoDesc,
@@ -85,5 +147,69 @@ func (c TailscaleTailnetSettingsCollector) Update(
 		prometheus.GaugeValue,
 		float64(settings.DevicesKeyDurationDays),
 	)
+
+	for _, setting := range tailnetSettingsBools {
+		ch <- prometheus.MustNewConstMetric(
+			setting.desc,
+			prometheus.GaugeValue,
+			boolAsFloat(setting.value(settings)),
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		tailnetSettingsLastChangedDesc,
+		prometheus.GaugeValue,
+		float64(c.observe(ctx, settings).Unix()),
+	)
+	return nil
+}
+
+// observe records settings and returns when they were last seen to change.
+// The first poll counts as a change, as the previous settings are unknown.
+func (c *TailscaleTailnetSettingsCollector) observe(
+	ctx context.Context,
+	settings *tailscale.TailnetSettings,
+) time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.previous == nil || *c.previous != *settings {
+		if c.previous != nil {
+			c.log.InfoContext(ctx, "Tailnet settings changed")
+		}
+		current := *settings
+		c.previous = &current
+		c.lastChanged = time.Now()
+	}
+	return c.lastChanged
+}
+
+// settingsState is the persisted form of the settings change tracking.
+type settingsState struct {
+	Previous    *tailscale.TailnetSettings `json:"previous"`
+	LastChanged time.Time                  `json:"last_changed"`
+}
+
+func (c *TailscaleTailnetSettingsCollector) saveState() (json.RawMessage, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.previous == nil {
+		return nil, nil
+	}
+	return json.Marshal(settingsState{Previous: c.previous, LastChanged: c.lastChanged})
+}
+
+func (c *TailscaleTailnetSettingsCollector) loadState(data json.RawMessage) error {
+	var state settingsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.previous = state.Previous
+	c.lastChanged = state.LastChanged
 	return nil
 }
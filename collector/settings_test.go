@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -39,9 +40,30 @@ func TestTailnetSettingsCollector_Update(t *testing.T) {
 				},
 			},
 			expectedMetrics: `
+# HELP tailscale_tailnet_settings_acls_externally_managed_on Whether ACLs are managed externally.
+# TYPE tailscale_tailnet_settings_acls_externally_managed_on gauge
+tailscale_tailnet_settings_acls_externally_managed_on 1
+# HELP tailscale_tailnet_settings_devices_approval_on Whether device approval is enabled.
+# TYPE tailscale_tailnet_settings_devices_approval_on gauge
+tailscale_tailnet_settings_devices_approval_on 1
+# HELP tailscale_tailnet_settings_devices_auto_updates_on Whether device auto-updates are enabled.
+# TYPE tailscale_tailnet_settings_devices_auto_updates_on gauge
+tailscale_tailnet_settings_devices_auto_updates_on 1
 # HELP tailscale_tailnet_settings_devices_key_duration_days Number of days before device key expiry.
 # TYPE tailscale_tailnet_settings_devices_key_duration_days gauge
 tailscale_tailnet_settings_devices_key_duration_days 90
+# HELP tailscale_tailnet_settings_network_flow_logging_on Whether network flow logging is enabled.
+# TYPE tailscale_tailnet_settings_network_flow_logging_on gauge
+tailscale_tailnet_settings_network_flow_logging_on 1
+# HELP tailscale_tailnet_settings_posture_identity_collection_on Whether device posture identity collection is enabled.
+# TYPE tailscale_tailnet_settings_posture_identity_collection_on gauge
+tailscale_tailnet_settings_posture_identity_collection_on 1
+# HELP tailscale_tailnet_settings_regional_routing_on Whether regional routing is enabled.
+# TYPE tailscale_tailnet_settings_regional_routing_on gauge
+tailscale_tailnet_settings_regional_routing_on 0
+# HELP tailscale_tailnet_settings_users_approval_on Whether user approval is enabled.
+# TYPE tailscale_tailnet_settings_users_approval_on gauge
+tailscale_tailnet_settings_users_approval_on 1
 # HELP tailscale_tailnet_settings_info Information about the Tailscale Tailnet settings.
 # TYPE tailscale_tailnet_settings_info gauge
 tailscale_tailnet_settings_info{acls_external_link="https://example.com/acls",acls_externally_managed_on="true",devices_approval_on="true",devices_auto_updates_on="true",network_flow_logging_on="true",posture_identity_collection_on="true",regional_routing_on="false",users_approval_on="true",users_role_allowed_to_join_external_tailnets="admin"} 1
@@ -89,10 +111,42 @@ tailscale_tailnet_settings_info{acls_external_link="https://example.com/acls",ac
 			tempCollector := &TestMetricCollector{metrics: metrics}
 			reg.MustRegister(tempCollector)
 
-			// Compare the metrics
-			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+			// The last changed timestamp is not deterministic, so leave it out.
+			if err := testutil.GatherAndCompare(
+				reg,
+				strings.NewReader(tt.expectedMetrics),
+				"tailscale_tailnet_settings_acls_externally_managed_on",
+				"tailscale_tailnet_settings_devices_approval_on",
+				"tailscale_tailnet_settings_devices_auto_updates_on",
+				"tailscale_tailnet_settings_devices_key_duration_days",
+				"tailscale_tailnet_settings_info",
+				"tailscale_tailnet_settings_network_flow_logging_on",
+				"tailscale_tailnet_settings_posture_identity_collection_on",
+				"tailscale_tailnet_settings_regional_routing_on",
+				"tailscale_tailnet_settings_users_approval_on",
+			); err != nil {
 				t.Errorf("metrics mismatch: %v", err)
 			}
 		})
 	}
 }
+
+func TestTailnetSettingsCollector_LastChanged(t *testing.T) {
+	previous := tailscale.TailnetSettings{DevicesApprovalOn: true}
+	collector := &TailscaleTailnetSettingsCollector{
+		log:         slog.Default(),
+		previous:    &previous,
+		lastChanged: time.Unix(1700000000, 0),
+	}
+
+	ctx := context.Background()
+	unchanged := previous
+	if got := collector.observe(ctx, &unchanged); !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected unchanged settings to keep the timestamp, got %v", got)
+	}
+
+	changed := tailscale.TailnetSettings{DevicesApprovalOn: false}
+	if got := collector.observe(ctx, &changed); !got.After(time.Unix(1700000000, 0)) {
+		t.Errorf("expected changed settings to update the timestamp, got %v", got)
+	}
+}
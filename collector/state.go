@@ -0,0 +1,176 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stateVersion is bumped when the state file format changes incompatibly.
+const stateVersion = 1
+
+// statefulCollector is implemented by collectors that keep inventory between
+// polls, so it can be persisted across restarts.
+type statefulCollector interface {
+	saveState() (json.RawMessage, error)
+	loadState(state json.RawMessage) error
+}
+
+// stateFile is the on-disk format of the state store.
+type stateFile struct {
+	Version    int                        `json:"version"`
+	Tailnet    string                     `json:"tailnet"`
+	Saved      time.Time                  `json:"saved"`
+	Collectors map[string]json.RawMessage `json:"collectors"`
+}
+
+// stateStore persists the state of stateful collectors to a JSON file.
+type stateStore struct {
+	mtx     sync.Mutex
+	path    string
+	tailnet string
+}
+
+// load reads the state file. A missing file is not an error, and a file for
+// another tailnet or format version is ignored.
+func (s *stateStore) load() (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding state file %s: %w", s.path, err)
+	}
+	if state.Version != stateVersion {
+		return nil, fmt.Errorf("state file %s has unsupported version %d", s.path, state.Version)
+	}
+	if state.Tailnet != s.tailnet {
+		return nil, fmt.Errorf("state file %s belongs to tailnet %q", s.path, state.Tailnet)
+	}
+	return state.Collectors, nil
+}
+
+// save writes the state of collectors to the state file. The file is
+// replaced atomically, so a crash never leaves a partially written file.
+func (s *stateStore) save(collectors map[string]Collector) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state := stateFile{
+		Version:    stateVersion,
+		Tailnet:    s.tailnet,
+		Saved:      time.Now().UTC(),
+		Collectors: make(map[string]json.RawMessage),
+	}
+	for name, c := range collectors {
+		sc, ok := c.(statefulCollector)
+		if !ok {
+			continue
+		}
+		data, err := sc.saveState()
+		if err != nil {
+			return fmt.Errorf("saving %s state: %w", name, err)
+		}
+		if data != nil {
+			state.Collectors[name] = data
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.path, 0o600, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// inherit carries the state of the collectors of prev that are still enabled
+// over, along with their statuses, forbidden backoff and error counters, so
+// rebuilding the collector does not reset counters or readiness.
+func (t *TailscaleCollector) inherit(prev *TailscaleCollector) {
+	t.scrapeErrors = prev.scrapeErrors
+
+	for name, c := range t.Collectors {
+		sc, ok := c.(statefulCollector)
+		if !ok {
+			continue
+		}
+		prevSC, ok := prev.Collectors[name].(statefulCollector)
+		if !ok {
+			continue
+		}
+		data, err := prevSC.saveState()
+		if err == nil && data != nil {
+			err = sc.loadState(data)
+		}
+		if err != nil {
+			t.logger.Warn(
+				"Ignoring previous collector state",
+				"collector",
+				name,
+				"error",
+				err.Error(),
+			)
+		}
+	}
+
+	statuses := prev.Status()
+	now := time.Now()
+	prev.forbidden.mtx.Lock()
+	defer prev.forbidden.mtx.Unlock()
+	for name := range t.Collectors {
+		if status, ok := statuses[name]; ok {
+			if t.statuses.statuses == nil {
+				t.statuses.statuses = make(map[string]CollectorStatus)
+			}
+			t.statuses.statuses[name] = status
+		}
+		if retryAt, ok := prev.forbidden.retryAt[name]; ok && retryAt.After(now) {
+			if t.forbidden.retryAt == nil {
+				t.forbidden.retryAt = make(map[string]time.Time)
+			}
+			t.forbidden.retryAt[name] = retryAt
+		}
+	}
+}
+
+// WriteFileAtomic calls write with a temporary file next to path and renames
+// it into place with the given permissions, so readers never see a partially
+// written file.
+func WriteFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestStateStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := &stateStore{path: path, tailnet: "example.com"}
+
+	poll := func(c *TailscaleUsersCollector, users []tailscale.User) map[string]float64 {
+		client := &MockTailscaleClient{usersClient: &MockUsersClient{users: users}}
+		ch := make(chan prometheus.Metric, 64)
+		if err := c.Update(context.Background(), client, ch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(ch)
+		return c.changes.state().Counts
+	}
+
+	before := &TailscaleUsersCollector{log: slog.Default()}
+	poll(before, []tailscale.User{{ID: "user-1"}, {ID: "user-2"}})
+	if err := store.save(map[string]Collector{usersSubsystem: before}); err != nil {
+		t.Fatalf("saving state: %v", err)
+	}
+
+	state, err := store.load()
+	if err != nil {
+		t.Fatalf("loading state: %v", err)
+	}
+	after := &TailscaleUsersCollector{log: slog.Default()}
+	if err := after.loadState(state[usersSubsystem]); err != nil {
+		t.Fatalf("restoring state: %v", err)
+	}
+
+	counts := poll(after, []tailscale.User{{ID: "user-1"}, {ID: "user-3"}})
+	if counts[changeAdded] != 1 || counts[changeRemoved] != 1 {
+		t.Errorf("expected one added and one removed user, got %v", counts)
+	}
+}
+
+func TestStateStore_Load(t *testing.T) {
+	dir := t.TempDir()
+
+	store := &stateStore{path: filepath.Join(dir, "missing.json"), tailnet: "example.com"}
+	if state, err := store.load(); err != nil || state != nil {
+		t.Errorf("expected no state and no error for a missing file, got %v, %v", state, err)
+	}
+
+	path := filepath.Join(dir, "other.json")
+	if err := os.WriteFile(path, []byte(`{"version":1,"tailnet":"other.com"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store = &stateStore{path: path, tailnet: "example.com"}
+	if _, err := store.load(); err == nil {
+		t.Error("expected an error for state of another tailnet")
+	}
+}
+
+func TestWithPrevious(t *testing.T) {
+	prev, err := NewTailscaleCollector(
+		slog.Default(),
+		nil,
+		"example.com",
+		WithCollectors(usersSubsystem),
+	)
+	if err != nil {
+		t.Fatalf("creating collector: %v", err)
+	}
+	prev.client = &MockTailscaleClient{
+		usersClient: &MockUsersClient{users: []tailscale.User{{ID: "user-1"}}},
+	}
+	prev.Refresh(context.Background())
+
+	next, err := NewTailscaleCollector(
+		slog.Default(),
+		nil,
+		"example.com",
+		WithCollectors(usersSubsystem),
+		WithPrevious(prev),
+	)
+	if err != nil {
+		t.Fatalf("creating collector: %v", err)
+	}
+	if next.LastSuccess().IsZero() {
+		t.Error("expected collector statuses to be carried over")
+	}
+	next.client = &MockTailscaleClient{
+		usersClient: &MockUsersClient{users: []tailscale.User{{ID: "user-2"}}},
+	}
+	next.Refresh(context.Background())
+
+	counts := next.Collectors[usersSubsystem].(*TailscaleUsersCollector).changes.state().Counts
+	if counts[changeAdded] != 1 || counts[changeRemoved] != 1 {
+		t.Errorf("expected one added and one removed user, got %v", counts)
+	}
+}
+
+func TestWithPrevious_ScrapeErrors(t *testing.T) {
+	prev, err := NewTailscaleCollector(
+		slog.Default(),
+		nil,
+		"example.com",
+		WithCollectors(usersSubsystem),
+	)
+	if err != nil {
+		t.Fatalf("creating collector: %v", err)
+	}
+	prev.client = &MockTailscaleClient{
+		usersClient: &MockUsersClient{
+			usersErr: apiError{Message: "unavailable", status: http.StatusServiceUnavailable},
+		},
+	}
+	prev.Refresh(context.Background())
+
+	next, err := NewTailscaleCollector(
+		slog.Default(),
+		nil,
+		"example.com",
+		WithCollectors(usersSubsystem),
+		WithPrevious(prev),
+	)
+	if err != nil {
+		t.Fatalf("creating collector: %v", err)
+	}
+	next.client = prev.client
+	next.Refresh(context.Background())
+
+	errors := testutil.ToFloat64(
+		next.scrapeErrors.WithLabelValues(usersSubsystem, errorKindServerError),
+	)
+	if errors != 2 {
+		t.Errorf("expected errors to be counted across the reload, got %v", errors)
+	}
+}
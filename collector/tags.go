@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const tagsSubsystem = "tags"
+
+var (
+	tagsDevicesDesc = newDesc(
+		tagsSubsystem,
+		"devices",
+		"Number of devices carrying a tag.",
+		[]string{"tag", "defined"},
+	)
+	tagsUnusedDesc = newDesc(
+		tagsSubsystem,
+		"unused",
+		"Tags defined in tagOwners that are not applied to any device.",
+		[]string{"tag"},
+	)
+	tagsUndefinedDevicesDesc = newDesc(
+		tagsSubsystem,
+		"undefined_devices",
+		"Number of devices carrying a tag that is not defined in tagOwners.",
+		[]string{"tag"},
+	)
+	tagsOwnerDevicesDesc = newDesc(
+		tagsSubsystem,
+		"owner_devices",
+		"Number of devices carrying a tag owned by the tag owner.",
+		[]string{"owner"},
+	)
+	tagsEmptyOwnerDevicesDesc = newDesc(
+		tagsSubsystem,
+		"empty_owner_devices",
+		"Number of devices carrying a tag whose owners are all empty groups.",
+		[]string{"tag"},
+	)
+)
+
+type TailscaleTagsCollector struct {
+	log *slog.Logger
+}
+
+func init() {
+	registerCollector(tagsSubsystem, NewTailscaleTagsCollector)
+}
+
+func NewTailscaleTagsCollector(config collectorConfig) (Collector, error) {
+	return &TailscaleTagsCollector{
+		log: config.logger,
+	}, nil
+}
+
+func (c TailscaleTagsCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting tags metrics")
+
+	acl, err := client.PolicyFile().Get(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale policy file",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	devices, err := client.Devices().List(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale devices",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	tagDevices := make(map[string]int)
+	ownerDevices := make(map[string]int)
+	for _, device := range devices {
+		// A device is counted once per owner, even if several of its tags
+		// share that owner.
+		owners := make(map[string]struct{})
+		for _, tag := range device.Tags {
+			tagDevices[tag]++
+			for _, owner := range acl.TagOwners[tag] {
+				owners[owner] = struct{}{}
+			}
+		}
+		for owner := range owners {
+			ownerDevices[owner]++
+		}
+	}
+
+	for tag, count := range tagDevices {
+		_, defined := acl.TagOwners[tag]
+		ch <- prometheus.MustNewConstMetric(
+			tagsDevicesDesc,
+			prometheus.GaugeValue,
+			float64(count),
+			tag,
+			strconv.FormatBool(defined),
+		)
+
+		if !defined {
+			ch <- prometheus.MustNewConstMetric(
+				tagsUndefinedDevicesDesc,
+				prometheus.GaugeValue,
+				float64(count),
+				tag,
+			)
+		}
+	}
+
+	for tag, owners := range acl.TagOwners {
+		count := tagDevices[tag]
+		if count == 0 {
+			ch <- prometheus.MustNewConstMetric(tagsUnusedDesc, prometheus.GaugeValue, 1, tag)
+			continue
+		}
+		if hasOnlyEmptyGroups(owners, acl.Groups) {
+			ch <- prometheus.MustNewConstMetric(
+				tagsEmptyOwnerDevicesDesc,
+				prometheus.GaugeValue,
+				float64(count),
+				tag,
+			)
+		}
+	}
+
+	for owner, count := range ownerDevices {
+		ch <- prometheus.MustNewConstMetric(
+			tagsOwnerDevicesDesc,
+			prometheus.GaugeValue,
+			float64(count),
+			owner,
+		)
+	}
+
+	return nil
+}
+
+// hasOnlyEmptyGroups reports whether owners is non-empty and consists only of
+// groups without members. An empty owner list means only admins may apply
+// the tag, which is not considered empty.
+func hasOnlyEmptyGroups(owners []string, groups map[string][]string) bool {
+	if len(owners) == 0 {
+		return false
+	}
+	for _, owner := range owners {
+		if !strings.HasPrefix(owner, "group:") || len(groups[owner]) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleTagsCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		expectedMetrics string
+		expectError     bool
+	}{
+		{
+			name: "successful collection with tags",
+			mockClient: &MockTailscaleClient{
+				policyFileClient: &MockPolicyFileClient{
+					acl: &tailscale.ACL{
+						Groups: map[string][]string{
+							"group:eng":     {"alice@example.com"},
+							"group:retired": {},
+						},
+						TagOwners: map[string][]string{
+							"tag:prod":   {"group:eng"},
+							"tag:legacy": {"group:retired"},
+							"tag:ci":     {"group:eng", "bob@example.com"},
+							"tag:unused": {"group:eng"},
+						},
+					},
+				},
+				devicesClient: &MockDevicesClient{
+					devices: []tailscale.Device{
+						{ID: "device-1", Tags: []string{"tag:prod", "tag:ci"}},
+						{ID: "device-2", Tags: []string{"tag:prod"}},
+						{ID: "device-3", Tags: []string{"tag:legacy", "tag:rogue"}},
+						{ID: "device-4"},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_tags_devices Number of devices carrying a tag.
+# TYPE tailscale_tags_devices gauge
+tailscale_tags_devices{defined="false",tag="tag:rogue"} 1
+tailscale_tags_devices{defined="true",tag="tag:ci"} 1
+tailscale_tags_devices{defined="true",tag="tag:legacy"} 1
+tailscale_tags_devices{defined="true",tag="tag:prod"} 2
+# HELP tailscale_tags_empty_owner_devices Number of devices carrying a tag whose owners are all empty groups.
+# TYPE tailscale_tags_empty_owner_devices gauge
+tailscale_tags_empty_owner_devices{tag="tag:legacy"} 1
+# HELP tailscale_tags_owner_devices Number of devices carrying a tag owned by the tag owner.
+# TYPE tailscale_tags_owner_devices gauge
+tailscale_tags_owner_devices{owner="bob@example.com"} 1
+tailscale_tags_owner_devices{owner="group:eng"} 2
+tailscale_tags_owner_devices{owner="group:retired"} 1
+# HELP tailscale_tags_undefined_devices Number of devices carrying a tag that is not defined in tagOwners.
+# TYPE tailscale_tags_undefined_devices gauge
+tailscale_tags_undefined_devices{tag="tag:rogue"} 1
+# HELP tailscale_tags_unused Tags defined in tagOwners that are not applied to any device.
+# TYPE tailscale_tags_unused gauge
+tailscale_tags_unused{tag="tag:unused"} 1
+`,
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscaleTagsCollector{
+				log: logger,
+			}
+
+			ch := make(chan prometheus.Metric, 32)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// Compare the metrics
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"tailscale.com/client/tailscale/v2"
 )
 
 const usersSubsystem = "users"
@@ -34,10 +37,69 @@ var (
 		"Unix timestamp when user was created",
 		[]string{"id", "login_name", "display_name"},
 	)
+	usersAddedDesc = newDesc(
+		usersSubsystem,
+		"added_total",
+		"Number of users added to the tailnet since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	usersRemovedDesc = newDesc(
+		usersSubsystem,
+		"removed_total",
+		"Number of users removed from the tailnet since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	usersRoleChangedDesc = newDesc(
+		usersSubsystem,
+		"role_changed_total",
+		"Number of user role changes since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	usersStatusChangedDesc = newDesc(
+		usersSubsystem,
+		"status_changed_total",
+		"Number of user lifecycle status changes, excluding changes between active and idle, since the exporter started, or since the state file was created if --state.path is set",
+		[]string{},
+	)
+	tailnetUsersDesc = newDesc(
+		tailnetSubsystem,
+		"users",
+		"Number of users in the tailnet",
+		[]string{"role", "status", "type"},
+	)
+)
+
+// usersAggregateKey holds the label values users are aggregated by.
+type usersAggregateKey struct {
+	role, status, userType string
+}
+
+const (
+	userChangeRole   = "role_changed"
+	userChangeStatus = "status_changed"
 )
 
+// usersChangeDescs maps the kinds of user changes to their counters.
+var usersChangeDescs = map[string]*prometheus.Desc{
+	changeAdded:      usersAddedDesc,
+	changeRemoved:    usersRemovedDesc,
+	userChangeRole:   usersRoleChangedDesc,
+	userChangeStatus: usersStatusChangedDesc,
+}
+
+// userState is the part of a user that change detection compares.
+type userState struct {
+	LoginName string `json:"login_name"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+}
+
 type TailscaleUsersCollector struct {
-	log *slog.Logger
+	log           *slog.Logger
+	aggregateOnly bool
+
+	changes  changeTracker[userState]
+	snapshot snapshot[[]tailscale.User]
 }
 
 func init() {
@@ -46,11 +108,12 @@ func init() {
 
 func NewTailscaleUsersCollector(config collectorConfig) (Collector, error) {
 	return &TailscaleUsersCollector{
-		log: config.logger,
+		log:           config.logger,
+		aggregateOnly: config.aggregateOnly,
 	}, nil
 }
 
-func (c TailscaleUsersCollector) Update(
+func (c *TailscaleUsersCollector) Update(
 	ctx context.Context,
 	client TailscaleClient,
 	ch chan<- prometheus.Metric,
@@ -67,9 +130,24 @@ func (c TailscaleUsersCollector) Update(
 		)
 		return err
 	}
+	c.snapshot.set(users)
+
+	aggregates := make(map[usersAggregateKey]int)
+	inventory := make(map[string]userState, len(users))
 
 	// User metrics
 	for _, user := range users {
+		inventory[user.ID] = userState{
+			LoginName: user.LoginName,
+			Role:      string(user.Role),
+			Status:    string(user.Status),
+		}
+		aggregates[usersAggregateKey{string(user.Role), string(user.Status), string(user.Type)}]++
+
+		if c.aggregateOnly {
+			continue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			usersInfoDesc, prometheus.GaugeValue, 1,
 			user.ID,
@@ -95,5 +173,67 @@ func (c TailscaleUsersCollector) Update(
 		}
 	}
 
+	for key, count := range aggregates {
+		ch <- prometheus.MustNewConstMetric(
+			tailnetUsersDesc, prometheus.GaugeValue, float64(count),
+			key.role, key.status, key.userType,
+		)
+	}
+
+	changes := c.changes.observe(inventory, func(id string, prev, cur *userState) []string {
+		return c.diff(ctx, id, prev, cur)
+	})
+	for kind, desc := range usersChangeDescs {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, changes[kind])
+	}
+
 	return nil
 }
+
+// diff logs and classifies the change of a user between two polls.
+func (c *TailscaleUsersCollector) diff(
+	ctx context.Context,
+	id string,
+	prev, cur *userState,
+) []string {
+	switch {
+	case prev == nil:
+		c.log.InfoContext(ctx, "User added",
+			"id", id, "login_name", cur.LoginName, "role", cur.Role)
+		return []string{changeAdded}
+	case cur == nil:
+		c.log.InfoContext(ctx, "User removed",
+			"id", id, "login_name", prev.LoginName, "role", prev.Role)
+		return []string{changeRemoved}
+	}
+
+	var kinds []string
+	if prev.Role != cur.Role {
+		c.log.InfoContext(ctx, "User role changed",
+			"id", id, "login_name", cur.LoginName, "old_role", prev.Role, "role", cur.Role)
+		kinds = append(kinds, userChangeRole)
+	}
+	if userLifecycleStatus(prev.Status) != userLifecycleStatus(cur.Status) {
+		c.log.InfoContext(ctx, "User status changed",
+			"id", id, "login_name", cur.LoginName, "old_status", prev.Status, "status", cur.Status)
+		kinds = append(kinds, userChangeStatus)
+	}
+	return kinds
+}
+
+// userLifecycleStatus returns the status of a user, treating idle users as
+// active, as idle only reflects their recent activity.
+func userLifecycleStatus(status string) string {
+	if status == string(tailscale.UserStatusIdle) {
+		return string(tailscale.UserStatusActive)
+	}
+	return status
+}
+
+func (c *TailscaleUsersCollector) saveState() (json.RawMessage, error) {
+	return c.changes.saveState()
+}
+
+func (c *TailscaleUsersCollector) loadState(state json.RawMessage) error {
+	return c.changes.loadState(state)
+}
@@ -19,6 +19,7 @@ func TestTailscaleUsersCollector_Update(t *testing.T) {
 		name            string
 		mockClient      *MockTailscaleClient
 		expectedMetrics string
+		aggregateOnly   bool
 		expectError     bool
 	}{
 		{
@@ -56,6 +57,55 @@ tailscale_users_info{display_name="User One",id="user-456",login_name="user",rol
 # HELP tailscale_users_last_seen_timestamp Unix timestamp when user was last seen
 # TYPE tailscale_users_last_seen_timestamp gauge
 tailscale_users_last_seen_timestamp{display_name="User One",id="user-456",login_name="user"} 1.62e+09
+# HELP tailscale_tailnet_users Number of users in the tailnet
+# TYPE tailscale_tailnet_users gauge
+tailscale_tailnet_users{role="admin",status="active",type="member"} 1
+# HELP tailscale_users_added_total Number of users added to the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_added_total counter
+tailscale_users_added_total 0
+# HELP tailscale_users_removed_total Number of users removed from the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_removed_total counter
+tailscale_users_removed_total 0
+# HELP tailscale_users_role_changed_total Number of user role changes since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_role_changed_total counter
+tailscale_users_role_changed_total 0
+# HELP tailscale_users_status_changed_total Number of user lifecycle status changes, excluding changes between active and idle, since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_status_changed_total counter
+tailscale_users_status_changed_total 0
+`,
+			expectError: false,
+		},
+		{
+			name: "aggregate only",
+			mockClient: &MockTailscaleClient{
+				usersClient: &MockUsersClient{
+					users: []tailscale.User{
+						{ID: "user-1", Type: "member", Role: "admin", Status: "active"},
+						{ID: "user-2", Type: "member", Role: "member", Status: "active"},
+						{ID: "user-3", Type: "member", Role: "member", Status: "active"},
+						{ID: "user-4", Type: "shared", Role: "member", Status: "idle"},
+					},
+				},
+			},
+			aggregateOnly: true,
+			expectedMetrics: `
+# HELP tailscale_tailnet_users Number of users in the tailnet
+# TYPE tailscale_tailnet_users gauge
+tailscale_tailnet_users{role="admin",status="active",type="member"} 1
+tailscale_tailnet_users{role="member",status="active",type="member"} 2
+tailscale_tailnet_users{role="member",status="idle",type="shared"} 1
+# HELP tailscale_users_added_total Number of users added to the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_added_total counter
+tailscale_users_added_total 0
+# HELP tailscale_users_removed_total Number of users removed from the tailnet since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_removed_total counter
+tailscale_users_removed_total 0
+# HELP tailscale_users_role_changed_total Number of user role changes since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_role_changed_total counter
+tailscale_users_role_changed_total 0
+# HELP tailscale_users_status_changed_total Number of user lifecycle status changes, excluding changes between active and idle, since the exporter started, or since the state file was created if --state.path is set
+# TYPE tailscale_users_status_changed_total counter
+tailscale_users_status_changed_total 0
 `,
 			expectError: false,
 		},
@@ -64,7 +114,8 @@ tailscale_users_last_seen_timestamp{display_name="User One",id="user-456",login_
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			collector := &TailscaleUsersCollector{
-				log: logger,
+				log:           logger,
+				aggregateOnly: tt.aggregateOnly,
 			}
 
 			// Buffer must be >= number of metrics emitted per device (currently 12) to avoid blocking Update.
@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const webhooksSubsystem = "webhooks"
+
+var (
+	webhooksEndpointsDesc = newDesc(
+		webhooksSubsystem,
+		"endpoints",
+		"Number of webhook endpoints configured for the tailnet.",
+		[]string{},
+	)
+	webhooksInfoDesc = newDesc(
+		webhooksSubsystem,
+		"info",
+		"Webhook endpoint information.",
+		[]string{"endpoint_id", "provider_type", "creator_login_name"},
+	)
+	webhooksSubscriptionDesc = newDesc(
+		webhooksSubsystem,
+		"subscription",
+		"Event types a webhook endpoint is subscribed to.",
+		[]string{"endpoint_id", "event_type"},
+	)
+	webhooksCreatedDesc = newDesc(
+		webhooksSubsystem,
+		"created_timestamp",
+		"Unix timestamp when the webhook endpoint was created.",
+		[]string{"endpoint_id"},
+	)
+	webhooksLastModifiedDesc = newDesc(
+		webhooksSubsystem,
+		"last_modified_timestamp",
+		"Unix timestamp when the webhook endpoint was last modified.",
+		[]string{"endpoint_id"},
+	)
+)
+
+type TailscaleWebhooksCollector struct {
+	log *slog.Logger
+}
+
+func init() {
+	registerCollector(webhooksSubsystem, NewTailscaleWebhooksCollector)
+}
+
+func NewTailscaleWebhooksCollector(config collectorConfig) (Collector, error) {
+	return &TailscaleWebhooksCollector{
+		log: config.logger,
+	}, nil
+}
+
+func (c TailscaleWebhooksCollector) Update(
+	ctx context.Context,
+	client TailscaleClient,
+	ch chan<- prometheus.Metric,
+) error {
+	c.log.DebugContext(ctx, "Collecting webhooks metrics")
+
+	webhooks, err := client.Webhooks().List(ctx)
+	if err != nil {
+		c.log.ErrorContext(
+			ctx,
+			"Error getting Tailscale webhooks",
+			"error",
+			err.Error(),
+		)
+		return err
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		webhooksEndpointsDesc,
+		prometheus.GaugeValue,
+		float64(len(webhooks)),
+	)
+
+	// The endpoint URL is deliberately not exported, as provider URLs such
+	// as Slack's embed the credentials needed to post to them.
+	for _, webhook := range webhooks {
+		ch <- prometheus.MustNewConstMetric(
+			webhooksInfoDesc, prometheus.GaugeValue, 1,
+			webhook.EndpointID,
+			string(webhook.ProviderType),
+			webhook.CreatorLoginName,
+		)
+
+		for _, subscription := range webhook.Subscriptions {
+			ch <- prometheus.MustNewConstMetric(
+				webhooksSubscriptionDesc, prometheus.GaugeValue, 1,
+				webhook.EndpointID, string(subscription),
+			)
+		}
+
+		if !webhook.Created.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				webhooksCreatedDesc, prometheus.GaugeValue, float64(webhook.Created.Unix()),
+				webhook.EndpointID,
+			)
+		}
+		if !webhook.LastModified.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				webhooksLastModifiedDesc, prometheus.GaugeValue, float64(webhook.LastModified.Unix()),
+				webhook.EndpointID,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"tailscale.com/client/tailscale/v2"
+)
+
+func TestTailscaleWebhooksCollector_Update(t *testing.T) {
+	logger := slog.Default()
+
+	tests := []struct {
+		name            string
+		mockClient      *MockTailscaleClient
+		expectedMetrics string
+		expectError     bool
+	}{
+		{
+			name: "successful collection with webhooks",
+			mockClient: &MockTailscaleClient{
+				webhooksClient: &MockWebhooksClient{
+					webhooks: []tailscale.Webhook{
+						{
+							EndpointID:       "webhook-123",
+							EndpointURL:      "https://hooks.slack.com/services/secret",
+							ProviderType:     tailscale.WebhookSlackProviderType,
+							CreatorLoginName: "alice@example.com",
+							Created:          time.Unix(1700000000, 0),
+							LastModified:     time.Unix(1710000000, 0),
+							Subscriptions: []tailscale.WebhookSubscriptionType{
+								tailscale.WebhookNodeCreated,
+								tailscale.WebhookPolicyUpdate,
+							},
+						},
+					},
+				},
+			},
+			expectedMetrics: `
+# HELP tailscale_webhooks_created_timestamp Unix timestamp when the webhook endpoint was created.
+# TYPE tailscale_webhooks_created_timestamp gauge
+tailscale_webhooks_created_timestamp{endpoint_id="webhook-123"} 1.7e+09
+# HELP tailscale_webhooks_endpoints Number of webhook endpoints configured for the tailnet.
+# TYPE tailscale_webhooks_endpoints gauge
+tailscale_webhooks_endpoints 1
+# HELP tailscale_webhooks_info Webhook endpoint information.
+# TYPE tailscale_webhooks_info gauge
+tailscale_webhooks_info{creator_login_name="alice@example.com",endpoint_id="webhook-123",provider_type="slack"} 1
+# HELP tailscale_webhooks_last_modified_timestamp Unix timestamp when the webhook endpoint was last modified.
+# TYPE tailscale_webhooks_last_modified_timestamp gauge
+tailscale_webhooks_last_modified_timestamp{endpoint_id="webhook-123"} 1.71e+09
+# HELP tailscale_webhooks_subscription Event types a webhook endpoint is subscribed to.
+# TYPE tailscale_webhooks_subscription gauge
+tailscale_webhooks_subscription{endpoint_id="webhook-123",event_type="nodeCreated"} 1
+tailscale_webhooks_subscription{endpoint_id="webhook-123",event_type="policyUpdate"} 1
+`,
+			expectError: false,
+		},
+		{
+			name: "no webhooks",
+			mockClient: &MockTailscaleClient{
+				webhooksClient: &MockWebhooksClient{},
+			},
+			expectedMetrics: `
+# HELP tailscale_webhooks_endpoints Number of webhook endpoints configured for the tailnet.
+# TYPE tailscale_webhooks_endpoints gauge
+tailscale_webhooks_endpoints 0
+`,
+			expectError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TailscaleWebhooksCollector{
+				log: logger,
+			}
+
+			ch := make(chan prometheus.Metric, 32)
+			ctx := context.Background()
+
+			err := collector.Update(ctx, tt.mockClient, ch)
+			close(ch)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Collect all metrics from the channel
+			var metrics []prometheus.Metric
+			for metric := range ch {
+				metrics = append(metrics, metric)
+			}
+
+			// Create a registry and register our metrics
+			reg := prometheus.NewRegistry()
+
+			// Create a temporary collector to hold our metrics for comparison
+			tempCollector := &TestMetricCollector{metrics: metrics}
+			reg.MustRegister(tempCollector)
+
+			// Compare the metrics
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expectedMetrics)); err != nil {
+				t.Errorf("metrics mismatch: %v", err)
+			}
+		})
+	}
+}